@@ -1,21 +1,39 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"database/sql"
+	"fmt"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/brij-812/HyperLinkOS/internal/analytics"
 	"github.com/brij-812/HyperLinkOS/internal/cache"
+	"github.com/brij-812/HyperLinkOS/internal/certs"
 	"github.com/brij-812/HyperLinkOS/internal/config"
 	"github.com/brij-812/HyperLinkOS/internal/database"
 	"github.com/brij-812/HyperLinkOS/internal/handlers"
+	"github.com/brij-812/HyperLinkOS/internal/logging"
+	"github.com/brij-812/HyperLinkOS/internal/metrics"
 	"github.com/brij-812/HyperLinkOS/internal/middleware"
+	"github.com/brij-812/HyperLinkOS/internal/ratelimit"
 	"github.com/brij-812/HyperLinkOS/internal/repository"
 	"github.com/brij-812/HyperLinkOS/internal/routes"
+	"github.com/brij-812/HyperLinkOS/internal/scheduler"
+	"github.com/brij-812/HyperLinkOS/internal/tracing"
+	"github.com/brij-812/HyperLinkOS/internal/utils"
 
 	"github.com/go-chi/chi/v5"
 	_ "github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
 )
 
 // connectWithRetry tries to connect to Postgres several times before giving up
@@ -27,57 +45,492 @@ func connectWithRetry(dsn string, retries int) (*sql.DB, error) {
 		if err == nil {
 			err = db.Ping()
 			if err == nil {
-				log.Println("✅ Connected to Postgres")
+				slog.Info("✅ Connected to Postgres")
 				return db, nil
 			}
 		}
-		log.Printf("⏳ Waiting for DB... (%d/%d)", i+1, retries)
+		slog.Info("⏳ Waiting for DB", "attempt", i+1, "retries", retries, "err", err)
 		time.Sleep(3 * time.Second)
 	}
 	return nil, err
 }
 
+// outagePolicy parses cache.on_outage.<name>; "local" is only valid where allowLocal is set.
+func outagePolicy(name, value string, def cache.Policy, allowLocal bool) cache.Policy {
+	p, err := cache.ParsePolicy(value, def)
+	if err == nil && p == cache.FailLocal && !allowLocal {
+		err = fmt.Errorf("policy %q is only supported for rate_limit", p)
+	}
+	if err != nil {
+		log.Fatalf("❌ Invalid cache.on_outage.%s: %v", name, err)
+	}
+	return p
+}
+
+// seconds converts a configured number of seconds: 0 means def, and a
+// negative value is passed through, which disables a scheduler job or an
+// http.Server timeout.
+func seconds(n int, def time.Duration) time.Duration {
+	if n == 0 {
+		return def
+	}
+	return time.Duration(n) * time.Second
+}
+
+// newScheduler registers the background jobs the storage backend supports.
+// Postgres deployments elect one replica per job through advisory locks.
+func newScheduler(cfg *config.Config, db *sql.DB, repo repository.Store, appCache cache.Cache) *scheduler.Scheduler {
+	var locker scheduler.Locker
+	if cfg.Database.Driver != "sqlite" {
+		locker = scheduler.NewPostgresLocker(db)
+	}
+	sched := scheduler.New(locker)
+	sc := cfg.Scheduler
+
+	if sweeper, ok := repo.(interface {
+		CleanupExpiredLinks(ctx context.Context, batchSize int) (int, error)
+	}); ok {
+		batch := sc.ExpirySweep.BatchSize
+		if batch <= 0 {
+			batch = 500
+		}
+		sched.Add(scheduler.Job{
+			Name:     "expiry-sweep",
+			Interval: seconds(sc.ExpirySweep.IntervalSeconds, 5*time.Minute),
+			Run: func(ctx context.Context) error {
+				_, err := sweeper.CleanupExpiredLinks(ctx, batch)
+				return err
+			},
+		})
+	}
+
+	sched.Add(scheduler.Job{
+		Name:     "cache-eviction",
+		Interval: seconds(sc.CacheEviction.IntervalSeconds, time.Minute),
+		Local:    true,
+		Run: func(ctx context.Context) error {
+			if n := cache.PurgeExpired(appCache); n > 0 {
+				slog.InfoContext(ctx, "🧹 Evicted expired cache entries", "entries", n)
+			}
+			return nil
+		},
+	})
+
+	if roller, ok := repo.(interface {
+		RollupClicks(ctx context.Context, since time.Time) error
+	}); ok {
+		days := sc.ClickRollup.Days
+		if days <= 0 {
+			days = 2
+		}
+		sched.Add(scheduler.Job{
+			Name:     "click-rollup",
+			Interval: seconds(sc.ClickRollup.IntervalSeconds, 15*time.Minute),
+			Run: func(ctx context.Context) error {
+				today := time.Now().UTC().Truncate(24 * time.Hour)
+				return roller.RollupClicks(ctx, today.AddDate(0, 0, -(days-1)))
+			},
+		})
+	}
+	return sched
+}
+
+// configureTLS prepares srv for server.tls.mode. It returns the plain HTTP
+// server that answers ACME challenges and redirects to HTTPS, or nil.
+func configureTLS(cfg *config.Config, db *sql.DB, domains certs.HostResolver, srv *http.Server) *http.Server {
+	t := cfg.Server.TLS
+	var challenges func(http.Handler) http.Handler
+	switch t.Mode {
+	case "":
+		return nil
+	case "static":
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			log.Fatalf("❌ Failed to load TLS certificate: %v", err)
+		}
+		srv.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+		slog.Info("🔒 TLS with certificate", "cert_file", t.CertFile)
+	case "acme":
+		var store certs.Store
+		switch t.ACME.Store {
+		case "", "file":
+			dir := t.ACME.Dir
+			if dir == "" {
+				dir = "acme-certs"
+			}
+			store = certs.NewDirStore(dir)
+		case "postgres":
+			if cfg.Database.Driver == "sqlite" {
+				log.Fatalf("❌ server.tls.acme.store postgres needs database.driver postgres")
+			}
+			store = certs.NewPostgresStore(db)
+		default:
+			log.Fatalf("❌ Unknown server.tls.acme.store %q (want file or postgres)", t.ACME.Store)
+		}
+
+		hosts := t.ACME.Hosts
+		if u, err := url.Parse(cfg.Server.PublicBaseURL); err == nil && u.Host != "" {
+			hosts = append(hosts, u.Host)
+		}
+		m, err := certs.NewManager(certs.ManagerConfig{
+			DirectoryURL: t.ACME.DirectoryURL,
+			CAFile:       t.ACME.CAFile,
+			Email:        t.ACME.Email,
+		}, store, certs.HostPolicy(hosts, domains))
+		if err != nil {
+			log.Fatalf("❌ Invalid server.tls.acme config: %v", err)
+		}
+		srv.TLSConfig = m.TLSConfig()
+		challenges = m.HTTPHandler
+		if t.HTTPPort == "" {
+			t.HTTPPort = "80"
+		}
+		slog.Info("🔒 TLS with ACME certificates for the listed hosts and verified custom domains", "hosts", hosts)
+	default:
+		log.Fatalf("❌ Unknown server.tls.mode %q (want static or acme)", t.Mode)
+	}
+	srv.TLSConfig.MinVersion = tls.VersionTLS12
+
+	if t.HTTPPort == "" {
+		return nil
+	}
+	handler := certs.RedirectHTTPS(cfg.Server.Port)
+	if challenges != nil {
+		handler = challenges(handler)
+	}
+	slog.Info("↪️ Plain HTTP redirects to HTTPS", "port", t.HTTPPort)
+	return &http.Server{
+		Addr:              ":" + t.HTTPPort,
+		Handler:           handler,
+		ReadHeaderTimeout: srv.ReadHeaderTimeout,
+		ReadTimeout:       srv.ReadTimeout,
+		WriteTimeout:      srv.WriteTimeout,
+		IdleTimeout:       srv.IdleTimeout,
+	}
+}
+
+// configureMetrics exports pool stats and mounts the Prometheus endpoint,
+// on r or, with metrics.addr set, on a separate admin server it returns.
+func configureMetrics(cfg *config.Config, db *sql.DB, rdb *redis.Client, r chi.Router) *http.Server {
+	if cfg.Metrics.Disabled {
+		return nil
+	}
+	driver := cfg.Database.Driver
+	if driver == "" {
+		driver = "postgres"
+	}
+	metrics.RegisterDB(db, driver)
+	if rdb != nil {
+		metrics.RegisterRedis(rdb)
+	}
+
+	path := cfg.Metrics.Path
+	if path == "" {
+		path = "/metrics/prometheus"
+	}
+	if cfg.Metrics.Addr == "" {
+		r.Handle(path, metrics.Handler())
+		slog.Info("📊 Prometheus metrics", "path", path)
+		return nil
+	}
+	mux := http.NewServeMux()
+	mux.Handle(path, metrics.Handler())
+	slog.Info("📊 Prometheus metrics", "addr", cfg.Metrics.Addr, "path", path)
+	return &http.Server{
+		Addr:              cfg.Metrics.Addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      30 * time.Second,
+	}
+}
+
+// listen opens srv's address up front so a taken port fails the start.
+func listen(srv *http.Server) net.Listener {
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		log.Fatalf("❌ Failed to listen on %s: %v", srv.Addr, err)
+	}
+	return ln
+}
+
+// rateLimitGroups converts rate_limit.groups; groups left out keep their defaults.
+func rateLimitGroups(cfg *config.Config) map[string]ratelimit.Group {
+	groups := middleware.DefaultRateLimits()
+	for name, g := range cfg.RateLimit.Groups {
+		algorithm := g.Algorithm
+		if algorithm == "" {
+			algorithm = ratelimit.SlidingWindow
+		}
+		window := time.Duration(g.WindowSeconds) * time.Second
+		if window == 0 {
+			window = time.Minute
+		}
+
+		group := make(ratelimit.Group, len(g.Limits))
+		for principal, limit := range g.Limits {
+			p := ratelimit.Policy{Algorithm: algorithm, Limit: limit, Window: window}
+			err := p.Validate()
+			if !ratelimit.ValidPrincipal(principal) {
+				err = fmt.Errorf("unknown principal (want ip, user, api_key or plan:<name>)")
+			}
+			if err != nil {
+				log.Fatalf("❌ Invalid rate_limit.groups.%s.limits.%s: %v", name, principal, err)
+			}
+			group[principal] = p
+		}
+		groups[name] = group
+		slog.Info("🚦 Rate limits", "group", name, "algorithm", algorithm, "limits", g.Limits, "window", window)
+	}
+	return groups
+}
+
 func main() {
 	// Load config
 	cfg := config.LoadConfig()
 
+	// Structured logging; the few remaining log calls are routed through it as well
+	if err := logging.Setup(cfg.Log.Level, cfg.Log.Format); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Tracing; deferred first so spans from the final flushes are exported too
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Headers:     cfg.Tracing.Headers,
+		ServiceName: cfg.Tracing.ServiceName,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		log.Fatalf("❌ Tracing setup failed: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.WarnContext(ctx, "⚠️ Failed to flush traces", "err", err)
+		}
+	}()
+
 	// Initialize JWT secret for middleware
 	middleware.InitJWTSecret(cfg.JWT.Secret)
 
-	// Build DSN
-	dsn := "host=" + cfg.Database.Host +
-		" port=" + cfg.Database.Port +
-		" user=" + cfg.Database.User +
-		" password=" + cfg.Database.Password +
-		" dbname=" + cfg.Database.Name +
-		" sslmode=" + cfg.Database.SSLMode
+	cacheMode := cfg.Cache.Mode
+	if cacheMode == "" {
+		cacheMode = "redis"
+	}
 
-	// Connect to DB
-	db, err := connectWithRetry(dsn, 10)
-	if err != nil {
-		log.Fatalf("❌ DB connection failed: %v", err)
+	// Redis is optional: only the redis/two_tier cache modes and the click stream use it
+	var (
+		rdb     *redis.Client
+		breaker *cache.Breaker
+	)
+	if cacheMode == "redis" || cacheMode == "two_tier" ||
+		cfg.Analytics.Sink == "stream" || cfg.Analytics.Stream.ConsumerEnabled {
+		redisAddr := cfg.Redis.Host + ":" + cfg.Redis.Port
+		rdb = cache.NewRedisClient(redisAddr, cfg.Redis.Password, cfg.Redis.DB, cfg.Redis.PoolSize)
+		defer rdb.Close()
+
+		breaker = cache.NewBreaker(
+			cfg.Redis.Breaker.FailureThreshold,
+			time.Duration(cfg.Redis.Breaker.OpenSeconds)*time.Second,
+			time.Duration(cfg.Redis.Breaker.MaxOpenSeconds)*time.Second,
+		)
+		rdb.AddHook(cache.TracingHook{}) // outermost, so breaker rejections are traced too
+		rdb.AddHook(breaker)
 	}
-	defer db.Close()
 
-	// Run migrations
-	database.RunMigrations(db, cfg, "up")
+	// Cache
+	var appCache cache.Cache
+	switch cacheMode {
+	case "redis":
+		appCache = cache.NewRedis(rdb, breaker)
+	case "local":
+		appCache = cache.NewLocal(cfg.Cache.LocalMaxEntries)
+	case "two_tier":
+		appCache = cache.NewTwoTier(
+			cache.NewLocal(cfg.Cache.LocalMaxEntries),
+			cache.NewRedis(rdb, breaker),
+			time.Duration(cfg.Cache.L1TTLSeconds)*time.Second,
+		)
+	case "none":
+		appCache = cache.Noop{}
+	default:
+		log.Fatalf("❌ Unknown cache.mode %q (want redis, local, two_tier or none)", cfg.Cache.Mode)
+	}
+	appCache = cache.Traced(appCache, cacheMode)
+	slog.Info("🗄️ Cache mode", "mode", cacheMode)
 
-	// Init Redis
-	redisAddr := cfg.Redis.Host + ":" + cfg.Redis.Port
-	cache.InitRedis(redisAddr, cfg.Redis.Password, cfg.Redis.DB)
+	// Degradation policies for a cache outage
+	rateLimitPolicy := outagePolicy("rate_limit", cfg.Cache.OnOutage.RateLimit, cache.FailLocal, true)
+	redirectPolicy := outagePolicy("redirects", cfg.Cache.OnOutage.Redirects, cache.FailOpen, false)
+	metricsPolicy := outagePolicy("metrics", cfg.Cache.OnOutage.Metrics, cache.FailOpen, false)
+
+	// Client IP: forwarding headers are honoured only from trusted proxies
+	if err := middleware.InitClientIP(cfg.Server.ClientIPHeader, cfg.Server.TrustedProxies); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Rate limiting: counters live in Redis when the cache does, else in process
+	var limiter ratelimit.Limiter = ratelimit.NewLocal(cfg.Cache.LocalMaxEntries)
+	if cacheMode == "redis" || cacheMode == "two_tier" {
+		limiter = ratelimit.NewRedis(rdb)
+	}
+	middleware.InitRateLimit(middleware.RateLimitConfig{
+		Limiter:  limiter,
+		Groups:   rateLimitGroups(cfg),
+		OnOutage: rateLimitPolicy,
+	})
+	if enum := cfg.RateLimit.Enumeration; !enum.Disabled {
+		middleware.InitEnumerationGuard(appCache, middleware.EnumerationConfig{
+			Window:    time.Duration(enum.WindowSeconds) * time.Second,
+			MinMisses: enum.MinMisses,
+			MissRatio: enum.MissRatio,
+			BlockFor:  time.Duration(enum.BlockSeconds) * time.Second,
+		})
+	}
+
+	// Storage backend
+	var (
+		db   *sql.DB
+		repo repository.Store
+	)
+	switch cfg.Database.Driver {
+	case "sqlite":
+		if cfg.Database.Path == "" {
+			cfg.Database.Path = "hyperlinkos.db"
+		}
+		db = database.NewSQLiteDB(cfg)
+		database.RunSQLiteMigrations(db, cfg, "up")
+		repo = repository.NewSQLiteRepo(db)
+	case "", "postgres":
+		// Build DSN
+		dsn := "host=" + cfg.Database.Host +
+			" port=" + cfg.Database.Port +
+			" user=" + cfg.Database.User +
+			" password=" + cfg.Database.Password +
+			" dbname=" + cfg.Database.Name +
+			" sslmode=" + cfg.Database.SSLMode
+
+		// Connect to DB
+		var err error
+		db, err = connectWithRetry(dsn, 10)
+		if err != nil {
+			log.Fatalf("❌ DB connection failed: %v", err)
+		}
+
+		// Run migrations
+		database.RunMigrations(db, cfg, "up")
+		pgRepo := repository.NewPostgresRepo(db, appCache)
+		pgRepo.RedirectCachePolicy = redirectPolicy
+		pgRepo.MetricsCachePolicy = metricsPolicy
+		repo = pgRepo
+	default:
+		log.Fatalf("❌ Unknown database.driver %q (want postgres or sqlite)", cfg.Database.Driver)
+	}
+	defer db.Close()
 
 	// Handlers
-	repo := repository.NewPostgresRepo(db)
-	urlHandler := handlers.NewURLHandler(repo)
+	codes, err := utils.NewCodeGenerator(
+		cfg.Shortener.Strategy,
+		cfg.Shortener.Length,
+		cfg.Shortener.NodeID,
+		repo,
+	)
+	if err != nil {
+		log.Fatalf("❌ Invalid shortener config: %v", err)
+	}
+	urlHandler := handlers.NewURLHandler(repo, codes, cfg.Shortener.MaxRetries)
+	urlHandler.BaseURL = cfg.Server.PublicBaseURL
+	urlHandler.Domains = repo
+	urlHandler.UnlockSecret = handlers.UnlockKey(cfg.JWT.Secret)
+	urlHandler.UnlockTTL = time.Duration(cfg.Shortener.UnlockTTLMinutes) * time.Minute
+	urlHandler.PendingFallbackURL = cfg.Shortener.Pending.FallbackURL
+	urlHandler.PendingStatus = cfg.Shortener.Pending.Status
+	urlHandler.PendingMessage = cfg.Shortener.Pending.Message
+	if s := cfg.Shortener.Pending.Status; s != 0 && (s < 400 || s > 599) {
+		log.Fatalf("❌ Invalid shortener.pending.status %d: want a 4xx or 5xx status", s)
+	}
+
+	// Click tracking: redirects queue events in memory and a background
+	// goroutine flushes them in batches to the database or the Redis stream
+	var clickSink analytics.ClickStore = repo
+	if cfg.Analytics.Sink == "stream" {
+		clickSink = cache.NewClickStreamProducer(
+			rdb,
+			cfg.Analytics.Stream.Key,
+			cfg.Analytics.Stream.MaxLen,
+		)
+		slog.Info("📤 Click events go to the Redis stream", "stream", cfg.Analytics.Stream.Key)
+	}
+	clickWriter := analytics.NewBufferedWriter(
+		clickSink,
+		cfg.Analytics.BufferSize,
+		cfg.Analytics.BatchSize,
+		time.Duration(cfg.Analytics.FlushIntervalSeconds)*time.Second,
+	)
+	clickWriter.Start()
+	defer clickWriter.Close()
+	urlHandler.Clicks = clickWriter
+	urlHandler.IPHashSalt = cfg.Analytics.IPHashSalt
+
+	// Optional stream consumer running in this process
+	if cfg.Analytics.Stream.ConsumerEnabled {
+		consumerName := cfg.Analytics.Stream.Consumer
+		if consumerName == "" {
+			consumerName, _ = os.Hostname()
+		}
+		consumer := analytics.NewStreamConsumer(rdb, repo, analytics.StreamConsumerConfig{
+			Stream:       cfg.Analytics.Stream.Key,
+			Group:        cfg.Analytics.Stream.Group,
+			Consumer:     consumerName,
+			BatchSize:    cfg.Analytics.Stream.BatchSize,
+			Block:        time.Duration(cfg.Analytics.Stream.BlockSeconds) * time.Second,
+			ClaimMinIdle: time.Duration(cfg.Analytics.Stream.ClaimIdleSeconds) * time.Second,
+		})
+		consumerCtx, stopConsumer := context.WithCancel(context.Background())
+		consumerDone := make(chan struct{})
+		defer func() {
+			stopConsumer()
+			<-consumerDone
+		}()
+		go func() {
+			defer close(consumerDone)
+			if err := consumer.Run(consumerCtx); err != nil {
+				slog.ErrorContext(consumerCtx, "❌ Click stream consumer exited", "err", err)
+			}
+		}()
+	}
+
+	// Background jobs: expiry sweep, cache eviction, click rollups
+	if !cfg.Scheduler.Disabled {
+		sched := newScheduler(cfg, db, repo, appCache)
+		sched.Start()
+		defer sched.Close()
+	}
+
 	userHandler := handlers.NewUserHandler(
 		db,
+		repo,
 		cfg.JWT.Secret,
 		cfg.JWT.Issuer,
 		cfg.JWT.AccessTokenExpiryMinutes,
+		cfg.JWT.RefreshTokenExpiryHours,
 	)
 
+	middleware.InitAPIKeys(repo)
+	apiKeyHandler := handlers.NewAPIKeyHandler(repo)
+	domainHandler := handlers.NewDomainHandler(repo)
+
 	// Router
 	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(middleware.Tracing)
+	r.Use(middleware.RealIP)
+	if !cfg.Metrics.Disabled {
+		r.Use(middleware.Metrics)
+	}
+	r.Use(middleware.AccessLog)
 
 	// ✅ USE YOUR EXISTING CORS MIDDLEWARE HERE
 	r.Use(middleware.CORS)
@@ -88,9 +541,70 @@ func main() {
 	})
 
 	// Register routes
-	routes.RegisterRoutes(r, urlHandler, userHandler)
+	routes.RegisterRoutes(r, urlHandler, userHandler, apiKeyHandler, domainHandler)
 
 	// Start server
-	log.Printf("🚀 Server running on :%s", cfg.Server.Port)
-	log.Fatal(http.ListenAndServe(":"+cfg.Server.Port, r))
+	srv := &http.Server{
+		Addr:              ":" + cfg.Server.Port,
+		Handler:           r,
+		ReadTimeout:       seconds(cfg.Server.ReadTimeoutSeconds, 15*time.Second),
+		ReadHeaderTimeout: seconds(cfg.Server.ReadHeaderTimeoutSeconds, 5*time.Second),
+		WriteTimeout:      seconds(cfg.Server.WriteTimeoutSeconds, 30*time.Second),
+		IdleTimeout:       seconds(cfg.Server.IdleTimeoutSeconds, 120*time.Second),
+	}
+
+	// Side servers: the plain HTTP redirect and the metrics admin port
+	var side []*http.Server
+	if httpSrv := configureTLS(cfg, db, repo, srv); httpSrv != nil {
+		side = append(side, httpSrv)
+	}
+	if adminSrv := configureMetrics(cfg, db, rdb, r); adminSrv != nil {
+		side = append(side, adminSrv)
+	}
+
+	ln := listen(srv)
+	sideLns := make([]net.Listener, len(side))
+	for i, s := range side {
+		sideLns[i] = listen(s)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1+len(side))
+	go func() {
+		if srv.TLSConfig != nil {
+			slog.Info("🚀 Server running", "addr", srv.Addr, "tls", true)
+			serveErr <- srv.ServeTLS(ln, "", "")
+			return
+		}
+		slog.Info("🚀 Server running", "addr", srv.Addr, "tls", false)
+		serveErr <- srv.Serve(ln)
+	}()
+	for i, s := range side {
+		go func() { serveErr <- s.Serve(sideLns[i]) }()
+	}
+
+	select {
+	case <-ctx.Done():
+		slog.Info("🛑 Shutdown signal received, draining connections")
+	case err := <-serveErr:
+		slog.Error("❌ Server stopped", "err", err)
+	}
+	stop() // a second signal kills the process immediately
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(),
+		seconds(cfg.Server.ShutdownTimeoutSeconds, 30*time.Second))
+	defer cancel()
+	for _, s := range side {
+		s.Shutdown(shutdownCtx)
+	}
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.WarnContext(shutdownCtx, "⚠️ Graceful shutdown timed out, closing remaining connections", "err", err)
+		srv.Close()
+	}
+
+	// Deferred cleanup now runs in reverse order: the scheduler and the stream
+	// consumer stop, buffered clicks are flushed, then the DB and Redis close.
+	slog.Info("👋 Server stopped, flushing background work")
 }
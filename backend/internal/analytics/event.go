@@ -0,0 +1,32 @@
+package analytics
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/brij-812/HyperLinkOS/internal/models"
+)
+
+// HashIP returns a salted, truncated SHA-256 of an IP so unique visitors can
+// be counted without storing addresses.
+func HashIP(salt, ip string) string {
+	if ip == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(salt + "|" + ip))
+	return hex.EncodeToString(sum[:16])
+}
+
+// NewClickEvent builds a click event for a redirect of code.
+func NewClickEvent(r *http.Request, code, ip, salt string) models.ClickEvent {
+	return models.ClickEvent{
+		Code:      code,
+		ClickedAt: time.Now().UTC(),
+		Referrer:  r.Referer(),
+		UserAgent: r.UserAgent(),
+		IPHash:    HashIP(salt, ip),
+		Country:   "", // placeholder until a GeoIP lookup is wired in
+	}
+}
@@ -0,0 +1,214 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/brij-812/HyperLinkOS/internal/cache"
+	"github.com/brij-812/HyperLinkOS/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	DefaultConsumerGroup = "click-writers"
+
+	defaultStreamBatch    = 500
+	defaultStreamBlock    = 5 * time.Second
+	defaultClaimMinIdle   = time.Minute
+	defaultClaimInterval  = 30 * time.Second
+	streamErrorBackoff    = time.Second
+	maxStreamErrorBackoff = 30 * time.Second
+)
+
+// StreamConsumerConfig tunes a StreamConsumer; zero values use defaults.
+type StreamConsumerConfig struct {
+	Stream    string
+	Group     string
+	Consumer  string // must be unique per running process
+	BatchSize int64
+	Block     time.Duration
+	// ClaimMinIdle is how long an entry must sit unacknowledged in another
+	// consumer's pending list before it is considered abandoned and reclaimed.
+	ClaimMinIdle time.Duration
+}
+
+// StreamConsumer drains the click stream through a consumer group and
+// bulk-inserts batches into a ClickStore. Entries are only acknowledged after
+// a successful write, so a crash leaves them pending for reclaim.
+type StreamConsumer struct {
+	rdb   *redis.Client
+	store ClickStore
+	cfg   StreamConsumerConfig
+}
+
+func NewStreamConsumer(rdb *redis.Client, store ClickStore, cfg StreamConsumerConfig) *StreamConsumer {
+	if cfg.Stream == "" {
+		cfg.Stream = cache.DefaultClickStream
+	}
+	if cfg.Group == "" {
+		cfg.Group = DefaultConsumerGroup
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultStreamBatch
+	}
+	if cfg.Block <= 0 {
+		cfg.Block = defaultStreamBlock
+	}
+	if cfg.ClaimMinIdle <= 0 {
+		cfg.ClaimMinIdle = defaultClaimMinIdle
+	}
+	return &StreamConsumer{rdb: rdb, store: store, cfg: cfg}
+}
+
+// Run consumes until ctx is cancelled.
+func (c *StreamConsumer) Run(ctx context.Context) error {
+	if err := c.ensureGroup(ctx); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "📥 Click stream consumer started", "consumer", c.cfg.Consumer, "stream", c.cfg.Stream, "group", c.cfg.Group)
+
+	// Entries this consumer read but never acknowledged before a restart.
+	if err := c.drainOwnPending(ctx); err != nil && ctx.Err() == nil {
+		slog.ErrorContext(ctx, "❌ Click stream pending drain error", "err", err)
+	}
+
+	lastClaim := time.Time{}
+	backoff := streamErrorBackoff
+	for ctx.Err() == nil {
+		if time.Since(lastClaim) >= defaultClaimInterval {
+			if err := c.reclaim(ctx); err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "❌ Click stream reclaim error", "err", err)
+			}
+			lastClaim = time.Now()
+		}
+
+		streams, err := c.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    c.cfg.Group,
+			Consumer: c.cfg.Consumer,
+			Streams:  []string{c.cfg.Stream, ">"},
+			Count:    c.cfg.BatchSize,
+			Block:    c.cfg.Block,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			slog.ErrorContext(ctx, "❌ Click stream read error", "retry_in", backoff, "err", err)
+			sleepCtx(ctx, backoff)
+			backoff = min(backoff*2, maxStreamErrorBackoff)
+			continue
+		}
+		backoff = streamErrorBackoff
+
+		for _, s := range streams {
+			if err := c.process(ctx, s.Messages); err != nil {
+				slog.ErrorContext(ctx, "❌ Click stream batch error", "err", err)
+			}
+		}
+	}
+
+	slog.InfoContext(ctx, "📥 Click stream consumer stopped", "consumer", c.cfg.Consumer)
+	return nil
+}
+
+func (c *StreamConsumer) ensureGroup(ctx context.Context) error {
+	// "0" so a freshly created group also picks up entries produced before it existed
+	err := c.rdb.XGroupCreateMkStream(ctx, c.cfg.Stream, c.cfg.Group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	return nil
+}
+
+// drainOwnPending re-processes entries already delivered to this consumer name.
+func (c *StreamConsumer) drainOwnPending(ctx context.Context) error {
+	for {
+		streams, err := c.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    c.cfg.Group,
+			Consumer: c.cfg.Consumer,
+			Streams:  []string{c.cfg.Stream, "0"},
+			Count:    c.cfg.BatchSize,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(streams) == 0 || len(streams[0].Messages) == 0 {
+			return nil
+		}
+		if err := c.process(ctx, streams[0].Messages); err != nil {
+			return err
+		}
+	}
+}
+
+// reclaim takes over entries left pending by consumers that crashed or hung.
+func (c *StreamConsumer) reclaim(ctx context.Context) error {
+	start := "0-0"
+	for {
+		msgs, next, err := c.rdb.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   c.cfg.Stream,
+			Group:    c.cfg.Group,
+			Consumer: c.cfg.Consumer,
+			MinIdle:  c.cfg.ClaimMinIdle,
+			Start:    start,
+			Count:    c.cfg.BatchSize,
+		}).Result()
+		if err != nil {
+			return err
+		}
+		if len(msgs) > 0 {
+			slog.InfoContext(ctx, "♻️ Reclaimed pending click events", "events", len(msgs))
+			if err := c.process(ctx, msgs); err != nil {
+				return err
+			}
+		}
+		if next == "0-0" || next == "" {
+			return nil
+		}
+		start = next
+	}
+}
+
+// process writes one batch and acknowledges it. Malformed entries are
+// acknowledged and dropped so they can't wedge the pending list forever.
+func (c *StreamConsumer) process(ctx context.Context, msgs []redis.XMessage) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	events := make([]models.ClickEvent, 0, len(msgs))
+	ids := make([]string, 0, len(msgs))
+	for _, m := range msgs {
+		ids = append(ids, m.ID)
+		ev, ok := cache.DecodeClickEvent(m.Values)
+		if !ok {
+			slog.WarnContext(ctx, "⚠️ Dropping malformed click event", "id", m.ID)
+			continue
+		}
+		events = append(events, ev)
+	}
+
+	if err := c.store.SaveClicks(ctx, events); err != nil {
+		// leave entries pending; they will be retried via reclaim
+		return err
+	}
+	return c.rdb.XAck(ctx, c.cfg.Stream, c.cfg.Group, ids...).Err()
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
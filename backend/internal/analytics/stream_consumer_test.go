@@ -0,0 +1,105 @@
+package analytics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/brij-812/HyperLinkOS/internal/cache"
+	"github.com/brij-812/HyperLinkOS/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	m := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: m.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return m, rdb
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStreamConsumerWritesAndAcks(t *testing.T) {
+	_, rdb := newTestRedis(t)
+	producer := cache.NewClickStreamProducer(rdb, "", 0)
+	batch := make([]models.ClickEvent, 3)
+	for i := range batch {
+		batch[i] = models.ClickEvent{Code: "abcd", ClickedAt: time.Now(), IPHash: "h"}
+	}
+	if err := producer.SaveClicks(context.Background(), batch); err != nil {
+		t.Fatalf("XADD failed: %v", err)
+	}
+	// malformed entry must be acknowledged and skipped, not retried forever
+	rdb.XAdd(context.Background(), &redis.XAddArgs{Stream: cache.DefaultClickStream, Values: map[string]interface{}{"junk": "1"}})
+
+	store := &fakeStore{}
+	c := NewStreamConsumer(rdb, store, StreamConsumerConfig{Consumer: "c1", Block: 50 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { c.Run(ctx); close(done) }()
+
+	waitFor(t, func() bool { return store.total() == 3 })
+	cancel()
+	<-done
+
+	pending, err := rdb.XPending(context.Background(), cache.DefaultClickStream, DefaultConsumerGroup).Result()
+	if err != nil {
+		t.Fatalf("XPENDING failed: %v", err)
+	}
+	if pending.Count != 0 {
+		t.Fatalf("expected no pending entries, got %d", pending.Count)
+	}
+	if store.batches[0][0].Code != "abcd" || store.batches[0][0].IPHash != "h" {
+		t.Fatalf("event fields not round-tripped: %+v", store.batches[0][0])
+	}
+}
+
+func TestStreamConsumerReclaimsAbandonedEntries(t *testing.T) {
+	m, rdb := newTestRedis(t)
+	ctx := context.Background()
+
+	producer := cache.NewClickStreamProducer(rdb, "", 0)
+	producer.SaveClicks(ctx, []models.ClickEvent{{Code: "lost", ClickedAt: time.Now()}})
+
+	// a consumer that crashes after reading but before acknowledging
+	rdb.XGroupCreateMkStream(ctx, cache.DefaultClickStream, DefaultConsumerGroup, "0")
+	if _, err := rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    DefaultConsumerGroup,
+		Consumer: "crashed",
+		Streams:  []string{cache.DefaultClickStream, ">"},
+	}).Result(); err != nil {
+		t.Fatalf("XREADGROUP failed: %v", err)
+	}
+	m.SetTime(time.Now().Add(2 * time.Minute))
+
+	store := &fakeStore{}
+	c := NewStreamConsumer(rdb, store, StreamConsumerConfig{
+		Consumer:     "survivor",
+		Block:        50 * time.Millisecond,
+		ClaimMinIdle: time.Minute,
+	})
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() { c.Run(runCtx); close(done) }()
+
+	waitFor(t, func() bool { return store.total() == 1 })
+	cancel()
+	<-done
+
+	if store.batches[0][0].Code != "lost" {
+		t.Fatalf("expected reclaimed event for code lost, got %+v", store.batches[0][0])
+	}
+}
@@ -0,0 +1,141 @@
+package analytics
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/brij-812/HyperLinkOS/internal/models"
+)
+
+// Recorder accepts click events from the redirect path.
+// Implementations must never block the caller.
+type Recorder interface {
+	Record(ctx context.Context, ev models.ClickEvent)
+}
+
+// ClickStore persists batches of click events (implemented by the repositories).
+type ClickStore interface {
+	SaveClicks(ctx context.Context, events []models.ClickEvent) error
+}
+
+const (
+	defaultBufferSize    = 10000
+	defaultBatchSize     = 500
+	defaultFlushInterval = 2 * time.Second
+
+	// flushTimeout bounds a single batch write so a hung database can't stall the writer forever.
+	flushTimeout = 10 * time.Second
+)
+
+// BufferedWriter queues click events in memory and flushes them to a
+// ClickStore in batches from a single background goroutine. When the queue
+// is full, events are dropped rather than slowing down redirects.
+type BufferedWriter struct {
+	store         ClickStore
+	events        chan models.ClickEvent
+	batchSize     int
+	flushInterval time.Duration
+
+	quit    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	dropped atomic.Int64
+}
+
+// NewBufferedWriter creates a writer; zero values fall back to sensible defaults.
+func NewBufferedWriter(store ClickStore, bufferSize, batchSize int, flushInterval time.Duration) *BufferedWriter {
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	if flushInterval <= 0 {
+		flushInterval = defaultFlushInterval
+	}
+	return &BufferedWriter{
+		store:         store,
+		events:        make(chan models.ClickEvent, bufferSize),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		quit:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+}
+
+// Start launches the background flush loop.
+func (w *BufferedWriter) Start() {
+	go w.run()
+}
+
+// Record enqueues an event without blocking; it is dropped if the buffer is full.
+func (w *BufferedWriter) Record(ctx context.Context, ev models.ClickEvent) {
+	select {
+	case w.events <- ev:
+	default:
+		if n := w.dropped.Add(1); n%1000 == 1 {
+			slog.WarnContext(ctx, "⚠️ Click buffer full, dropping events", "dropped", n)
+		}
+	}
+}
+
+// Dropped reports how many events were discarded because the buffer was full.
+func (w *BufferedWriter) Dropped() int64 {
+	return w.dropped.Load()
+}
+
+// Close stops the loop after flushing everything already queued.
+func (w *BufferedWriter) Close() {
+	w.once.Do(func() { close(w.quit) })
+	<-w.done
+}
+
+func (w *BufferedWriter) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]models.ClickEvent, 0, w.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+		err := w.store.SaveClicks(ctx, batch)
+		cancel()
+		if err != nil {
+			slog.ErrorContext(ctx, "❌ Failed to flush click events", "events", len(batch), "err", err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case ev := <-w.events:
+			batch = append(batch, ev)
+			if len(batch) >= w.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-w.quit:
+			// drain whatever is still queued, then exit
+			for {
+				select {
+				case ev := <-w.events:
+					batch = append(batch, ev)
+					if len(batch) >= w.batchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package analytics
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brij-812/HyperLinkOS/internal/models"
+)
+
+type fakeStore struct {
+	mu      sync.Mutex
+	batches [][]models.ClickEvent
+	block   chan struct{}
+}
+
+func (s *fakeStore) SaveClicks(_ context.Context, events []models.ClickEvent) error {
+	if s.block != nil {
+		<-s.block
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, append([]models.ClickEvent(nil), events...))
+	return nil
+}
+
+func (s *fakeStore) total() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, b := range s.batches {
+		n += len(b)
+	}
+	return n
+}
+
+func TestBufferedWriterBatchesAndFlushesOnClose(t *testing.T) {
+	store := &fakeStore{}
+	w := NewBufferedWriter(store, 100, 10, time.Hour)
+	w.Start()
+
+	for i := 0; i < 25; i++ {
+		w.Record(context.Background(), models.ClickEvent{Code: "abcd"})
+	}
+	w.Close()
+
+	if got := store.total(); got != 25 {
+		t.Fatalf("expected 25 events flushed, got %d", got)
+	}
+	for _, b := range store.batches {
+		if len(b) > 10 {
+			t.Fatalf("batch of %d exceeds batch size 10", len(b))
+		}
+	}
+}
+
+func TestBufferedWriterDropsWhenFull(t *testing.T) {
+	store := &fakeStore{block: make(chan struct{})}
+	w := NewBufferedWriter(store, 5, 1, time.Hour)
+	w.Start()
+
+	// the first event is picked up and blocks the store; the rest fill the buffer
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 50; i++ {
+			w.Record(context.Background(), models.ClickEvent{Code: "abcd"})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Record blocked while the store was stalled")
+	}
+	if w.Dropped() == 0 {
+		t.Fatal("expected events to be dropped once the buffer filled up")
+	}
+
+	close(store.block)
+	w.Close()
+}
+
+func TestHashIP(t *testing.T) {
+	if HashIP("salt", "1.2.3.4") != HashIP("salt", "1.2.3.4") {
+		t.Fatal("expected stable hash for the same ip")
+	}
+	if HashIP("salt", "1.2.3.4") == HashIP("other", "1.2.3.4") {
+		t.Fatal("expected salt to change the hash")
+	}
+	if HashIP("salt", "") != "" {
+		t.Fatal("expected empty hash for unknown ip")
+	}
+}
@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrUnavailable is returned for Redis commands while the circuit is open.
+var ErrUnavailable = errors.New("cache unavailable")
+
+const (
+	defaultBreakerThreshold  = 5
+	defaultBreakerMinBackoff = time.Second
+	defaultBreakerMaxBackoff = 30 * time.Second
+)
+
+// Breaker is a circuit breaker installed as a go-redis hook. After
+// threshold consecutive failures it opens: commands fail fast with
+// ErrUnavailable instead of waiting on a dead server. Once the backoff
+// has passed, commands are let through again as probes: the first success
+// closes the circuit, a failure reopens it with the backoff doubled (up to max).
+type Breaker struct {
+	mu         sync.Mutex
+	threshold  int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	now        func() time.Time
+
+	failures  int
+	open      bool
+	openUntil time.Time
+	backoff   time.Duration
+}
+
+// NewBreaker creates a breaker; zero values pick 5 failures and a 1s–30s backoff.
+func NewBreaker(threshold int, minBackoff, maxBackoff time.Duration) *Breaker {
+	if threshold <= 0 {
+		threshold = defaultBreakerThreshold
+	}
+	if minBackoff <= 0 {
+		minBackoff = defaultBreakerMinBackoff
+	}
+	if maxBackoff < minBackoff {
+		maxBackoff = max(defaultBreakerMaxBackoff, minBackoff)
+	}
+	return &Breaker{
+		threshold:  threshold,
+		minBackoff: minBackoff,
+		maxBackoff: maxBackoff,
+		now:        time.Now,
+	}
+}
+
+// Available reports whether commands currently reach Redis (closed circuit,
+// or open with the backoff elapsed so probes are due).
+func (b *Breaker) Available() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.open || !b.now().Before(b.openUntil)
+}
+
+func (b *Breaker) record(ctx context.Context, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if errors.Is(err, context.Canceled) {
+		return // the caller gave up; says nothing about Redis
+	}
+	if !isOutage(err) {
+		if b.open {
+			slog.InfoContext(ctx, "✅ Redis is back, closing circuit")
+		}
+		b.failures, b.open, b.backoff = 0, false, 0
+		return
+	}
+
+	if b.open {
+		if b.now().Before(b.openUntil) {
+			return // another probe already failed and reopened the circuit
+		}
+		b.backoff = min(b.backoff*2, b.maxBackoff)
+		b.openUntil = b.now().Add(b.backoff)
+		slog.WarnContext(ctx, "🔌 Redis probe failed, circuit stays open", "open_for", b.backoff, "err", err)
+		return
+	}
+
+	b.failures++
+	if !b.open && b.failures >= b.threshold {
+		b.open = true
+		b.backoff = b.minBackoff
+		b.openUntil = b.now().Add(b.backoff)
+		slog.ErrorContext(ctx, "🔌 Redis keeps failing, opening circuit", "failures", b.failures, "open_for", b.backoff, "err", err)
+	}
+}
+
+// isOutage tells connection and server trouble apart from normal replies
+// (redis.Nil) and callers giving up.
+func isOutage(err error) bool {
+	if err == nil || errors.Is(err, redis.Nil) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	// Replies such as WRONGTYPE mean Redis is up; anything else is treated as an outage.
+	var redisErr redis.Error
+	return !errors.As(err, &redisErr)
+}
+
+func (b *Breaker) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (b *Breaker) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if !b.Available() {
+			cmd.SetErr(ErrUnavailable)
+			return ErrUnavailable
+		}
+		err := next(ctx, cmd)
+		b.record(ctx, err)
+		return err
+	}
+}
+
+func (b *Breaker) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if !b.Available() {
+			for _, cmd := range cmds {
+				cmd.SetErr(ErrUnavailable)
+			}
+			return ErrUnavailable
+		}
+		err := next(ctx, cmds)
+		b.record(ctx, err)
+		return err
+	}
+}
@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Cache is the key/value store behind link lookups, metrics and rate
+// limiting. Implementations never fail a request: errors are logged and
+// reported as a miss, so a cache outage only costs latency.
+type Cache interface {
+	// Get returns the value stored under key, or false on a miss.
+	Get(ctx context.Context, key string) (string, bool)
+	// Set stores value under key for ttl (0 = no expiry).
+	Set(ctx context.Context, key, value string, ttl time.Duration)
+	// Delete removes keys; missing keys are ignored.
+	Delete(ctx context.Context, keys ...string)
+	// Incr atomically increments the counter at key and returns the new
+	// value. ttl is applied when the counter is created.
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+}
+
+// Available reports whether c can currently reach its backing store.
+// Caches that cannot fail (Local, Noop) are always available.
+func Available(c Cache) bool {
+	if a, ok := c.(interface{ Available() bool }); ok {
+		return a.Available()
+	}
+	return true
+}
+
+// PurgeExpired evicts expired keys from an in-process cache and returns
+// how many were removed. Caches that expire keys themselves report 0.
+func PurgeExpired(c Cache) int {
+	if p, ok := c.(interface{ PurgeExpired() int }); ok {
+		return p.PurgeExpired()
+	}
+	return 0
+}
+
+// Policy says what a feature does when its cache is unavailable.
+type Policy string
+
+const (
+	// FailOpen carries on without the cache (e.g. read through to the database).
+	FailOpen Policy = "open"
+	// FailClosed rejects the request rather than let load through.
+	FailClosed Policy = "closed"
+	// FailLocal switches to an in-process fallback; only rate limiting supports it.
+	FailLocal Policy = "local"
+)
+
+// ParsePolicy validates a configured policy; empty selects def.
+func ParsePolicy(s string, def Policy) (Policy, error) {
+	switch p := Policy(s); p {
+	case "":
+		return def, nil
+	case FailOpen, FailClosed, FailLocal:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown cache outage policy %q (want open, closed or local)", s)
+}
+
+// Noop is a Cache that stores nothing; every Get misses and Incr always
+// returns 1, so nothing built on it ever rate-limits.
+type Noop struct{}
+
+func (Noop) Get(context.Context, string) (string, bool)                 { return "", false }
+func (Noop) Set(context.Context, string, string, time.Duration)         {}
+func (Noop) Delete(context.Context, ...string)                          {}
+func (Noop) Incr(context.Context, string, time.Duration) (int64, error) { return 1, nil }
+
+// OrNoop returns c, or Noop if c is nil.
+func OrNoop(c Cache) Cache {
+	if c == nil {
+		return Noop{}
+	}
+	return c
+}
@@ -0,0 +1,241 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestLocalEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := NewLocal(2)
+
+	c.Set(ctx, "a", "1", 0)
+	c.Set(ctx, "b", "2", 0)
+	c.Get(ctx, "a") // a is now the most recently used
+	c.Set(ctx, "c", "3", 0)
+
+	if _, ok := c.Get(ctx, "b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	if v, ok := c.Get(ctx, "a"); !ok || v != "1" {
+		t.Errorf("expected a=1, got %q %v", v, ok)
+	}
+	if c.Len() != 2 {
+		t.Errorf("expected 2 entries, got %d", c.Len())
+	}
+}
+
+func TestLocalTTLAndIncr(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1_700_000_000, 0)
+	c := NewLocal(0)
+	c.now = func() time.Time { return now }
+
+	c.Set(ctx, "k", "v", time.Minute)
+	for i := int64(1); i <= 3; i++ {
+		if n, err := c.Incr(ctx, "counter", time.Minute); err != nil || n != i {
+			t.Fatalf("Incr #%d = %d, %v", i, n, err)
+		}
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := c.Get(ctx, "k"); ok {
+		t.Error("expected k to expire")
+	}
+	if n, _ := c.Incr(ctx, "counter", time.Minute); n != 1 {
+		t.Errorf("expected expired counter to restart at 1, got %d", n)
+	}
+
+	c.Delete(ctx, "counter", "missing")
+	if _, ok := c.Get(ctx, "counter"); ok {
+		t.Error("expected counter to be deleted")
+	}
+}
+
+func TestLocalPurgeExpired(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1_700_000_000, 0)
+	c := NewLocal(0)
+	c.now = func() time.Time { return now }
+
+	c.Set(ctx, "short", "1", time.Minute)
+	c.Set(ctx, "long", "2", time.Hour)
+	c.Set(ctx, "forever", "3", 0)
+
+	now = now.Add(2 * time.Minute)
+	if n := PurgeExpired(c); n != 1 {
+		t.Errorf("expected 1 purged entry, got %d", n)
+	}
+	if c.Len() != 2 {
+		t.Errorf("expected 2 entries left, got %d", c.Len())
+	}
+	if n := PurgeExpired(Noop{}); n != 0 {
+		t.Errorf("expected Noop to purge nothing, got %d", n)
+	}
+}
+
+func TestTwoTier(t *testing.T) {
+	ctx := context.Background()
+	m := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: m.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	l1 := NewLocal(0)
+	c := NewTwoTier(l1, NewRedis(rdb, nil), time.Minute)
+
+	c.Set(ctx, "k", "v", time.Hour)
+	if got, _ := m.Get("k"); got != "v" {
+		t.Errorf("expected write-through to Redis, got %q", got)
+	}
+
+	// served from L1 even after Redis lost the key
+	m.Del("k")
+	if v, ok := c.Get(ctx, "k"); !ok || v != "v" {
+		t.Errorf("expected L1 hit, got %q %v", v, ok)
+	}
+
+	// an L2 hit is copied into L1
+	m.Set("other", "x")
+	c.Get(ctx, "other")
+	if v, ok := l1.Get(ctx, "other"); !ok || v != "x" {
+		t.Errorf("expected L2 hit to fill L1, got %q %v", v, ok)
+	}
+
+	c.Delete(ctx, "other")
+	if _, ok := c.Get(ctx, "other"); ok {
+		t.Error("expected delete to clear both tiers")
+	}
+
+	// counters are shared through Redis, not kept per node
+	c.Incr(ctx, "rate", time.Minute)
+	if n, _ := NewRedis(rdb, nil).Incr(ctx, "rate", time.Minute); n != 2 {
+		t.Errorf("expected Redis counter 2, got %d", n)
+	}
+	if ttl := m.TTL("rate"); ttl != time.Minute {
+		t.Errorf("expected counter TTL 1m, got %v", ttl)
+	}
+}
+
+func TestRedisUnreachableIsAMiss(t *testing.T) {
+	m := miniredis.RunT(t)
+	addr := m.Addr()
+	m.Close()
+
+	rdb := NewRedisClient(addr, "", 0, 0) // must not exit the process
+	t.Cleanup(func() { rdb.Close() })
+
+	c := NewRedis(rdb, nil)
+	if _, ok := c.Get(context.Background(), "k"); ok {
+		t.Error("expected a miss while Redis is down")
+	}
+	if _, err := c.Incr(context.Background(), "k", time.Minute); err == nil {
+		t.Error("expected Incr to report the outage")
+	}
+}
+
+func TestBreakerOpensAndRecovers(t *testing.T) {
+	ctx := context.Background()
+	m := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: m.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { rdb.Close() })
+
+	now := time.Unix(1_700_000_000, 0)
+	b := NewBreaker(2, time.Second, 4*time.Second)
+	b.now = func() time.Time { return now }
+	rdb.AddHook(b)
+	c := NewRedis(rdb, b)
+
+	if err := rdb.Set(ctx, "k", "v", 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get(ctx, "missing"); ok || !c.Available() {
+		t.Fatal("a plain miss must not count as a failure")
+	}
+
+	m.Close()
+	c.Get(ctx, "k")
+	c.Get(ctx, "k")
+	if c.Available() {
+		t.Fatal("expected circuit to open after 2 failures")
+	}
+	if err := rdb.Get(ctx, "k").Err(); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected fast failure while open, got %v", err)
+	}
+
+	// failed probe doubles the backoff
+	now = now.Add(time.Second)
+	if !c.Available() {
+		t.Fatal("expected a probe to be due")
+	}
+	c.Get(ctx, "k")
+	now = now.Add(time.Second)
+	if c.Available() {
+		t.Fatal("expected backoff of 2s after a failed probe")
+	}
+
+	// successful probe closes the circuit
+	if err := m.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Second)
+	c.Set(ctx, "k", "v2", 0)
+	if v, ok := c.Get(ctx, "k"); !ok || v != "v2" || !c.Available() {
+		t.Fatalf("expected recovery, got %q %v", v, ok)
+	}
+}
+
+func TestTraced(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	defer otel.SetTracerProvider(prev)
+
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	rdb.Ping(ctx) // open the connection first, so its handshake isn't recorded
+	rdb.AddHook(TracingHook{})
+	c := Traced(NewTwoTier(NewLocal(10), NewRedis(rdb, nil), 0), "two_tier")
+
+	c.Set(ctx, "shorturl:abc", "https://example.com", time.Minute)
+	c.Get(ctx, "shorturl:abc")
+	c.Get(ctx, "shorturl:missing")
+	if !Available(c) {
+		t.Error("expected Available to reach the wrapped cache")
+	}
+
+	var names []string
+	var hits []bool
+	for _, s := range rec.Ended() {
+		names = append(names, s.Name())
+		for _, kv := range s.Attributes() {
+			switch {
+			case kv.Key == "cache.hit":
+				hits = append(hits, kv.Value.AsBool())
+			case kv.Key == "cache.key_prefix" && kv.Value.AsString() != "shorturl":
+				t.Errorf("expected key prefix shorturl, got %q", kv.Value.AsString())
+			}
+		}
+		if s.Status().Code == codes.Error {
+			t.Errorf("expected no errors, %s failed: %s", s.Name(), s.Status().Description)
+		}
+	}
+	// The hit is served from L1 and never reaches Redis; the miss falls through.
+	want := []string{"redis SET", "cache set", "cache get", "redis GET", "cache get"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("expected spans %v, got %v", want, names)
+	}
+	if len(hits) != 2 || !hits[0] || hits[1] {
+		t.Errorf("expected a hit then a miss, got %v", hits)
+	}
+}
@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/brij-812/HyperLinkOS/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	DefaultClickStream = "clicks:stream"
+
+	defaultClickStreamMaxLen = 1_000_000
+)
+
+// ClickStreamProducer publishes click events to a Redis stream with XADD.
+// It is an analytics.ClickStore: redirects hand events to an
+// analytics.BufferedWriter in front of it, so they never wait on Redis.
+type ClickStreamProducer struct {
+	rdb    *redis.Client
+	stream string
+	maxLen int64
+}
+
+// NewClickStreamProducer creates a producer; the stream is trimmed
+// approximately to maxLen entries so an idle consumer can't exhaust memory.
+func NewClickStreamProducer(rdb *redis.Client, stream string, maxLen int64) *ClickStreamProducer {
+	if stream == "" {
+		stream = DefaultClickStream
+	}
+	if maxLen <= 0 {
+		maxLen = defaultClickStreamMaxLen
+	}
+	return &ClickStreamProducer{rdb: rdb, stream: stream, maxLen: maxLen}
+}
+
+// SaveClicks appends a batch of events to the stream in one pipeline.
+// While the circuit breaker is open the batch is dropped without an error;
+// the breaker already reports the outage.
+func (p *ClickStreamProducer) SaveClicks(ctx context.Context, events []models.ClickEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	pipe := p.rdb.Pipeline()
+	for _, ev := range events {
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: p.stream,
+			MaxLen: p.maxLen,
+			Approx: true,
+			Values: EncodeClickEvent(ev),
+		})
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, ErrUnavailable) {
+		return err
+	}
+	return nil
+}
+
+// EncodeClickEvent flattens a click event into stream fields.
+func EncodeClickEvent(ev models.ClickEvent) map[string]interface{} {
+	return map[string]interface{}{
+		"code":    ev.Code,
+		"ts":      ev.ClickedAt.UnixNano(),
+		"ref":     ev.Referrer,
+		"ua":      ev.UserAgent,
+		"ip":      ev.IPHash,
+		"country": ev.Country,
+	}
+}
+
+// DecodeClickEvent is the inverse of EncodeClickEvent.
+func DecodeClickEvent(values map[string]interface{}) (models.ClickEvent, bool) {
+	str := func(k string) string {
+		s, _ := values[k].(string)
+		return s
+	}
+
+	code := str("code")
+	ts, err := strconv.ParseInt(str("ts"), 10, 64)
+	if code == "" || err != nil {
+		return models.ClickEvent{}, false
+	}
+	return models.ClickEvent{
+		Code:      code,
+		ClickedAt: time.Unix(0, ts).UTC(),
+		Referrer:  str("ref"),
+		UserAgent: str("ua"),
+		IPHash:    str("ip"),
+		Country:   str("country"),
+	}, true
+}
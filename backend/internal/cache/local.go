@@ -0,0 +1,154 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const defaultLocalMaxEntries = 10_000
+
+// Local is an in-process LRU cache with per-key TTLs. It needs no external
+// service, which makes it the cache for single-node deployments and tests.
+type Local struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List // front = most recently used
+	items      map[string]*list.Element
+	now        func() time.Time
+}
+
+type localEntry struct {
+	key       string
+	value     string
+	expiresAt time.Time // zero = never
+}
+
+// NewLocal creates a cache holding at most maxEntries keys (default 10000);
+// the least recently used key is evicted first.
+func NewLocal(maxEntries int) *Local {
+	if maxEntries <= 0 {
+		maxEntries = defaultLocalMaxEntries
+	}
+	return &Local{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+		now:        time.Now,
+	}
+}
+
+func (c *Local) Get(_ context.Context, key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e := c.lookup(key)
+	if e == nil {
+		return "", false
+	}
+	return e.Value.(*localEntry).value, true
+}
+
+func (c *Local) Set(_ context.Context, key, value string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.store(key, value, ttl)
+}
+
+func (c *Local) Delete(_ context.Context, keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		if e, ok := c.items[key]; ok {
+			c.remove(e)
+		}
+	}
+}
+
+func (c *Local) Incr(_ context.Context, key string, ttl time.Duration) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e := c.lookup(key)
+	if e == nil {
+		c.store(key, "1", ttl)
+		return 1, nil
+	}
+	entry := e.Value.(*localEntry)
+	n, err := strconv.ParseInt(entry.value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	n++
+	entry.value = strconv.FormatInt(n, 10)
+	return n, nil
+}
+
+// Len returns the number of keys held, including expired ones not yet evicted.
+func (c *Local) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// PurgeExpired evicts every expired key and returns how many were removed.
+// Expired keys are otherwise only dropped when read or pushed out by newer ones.
+func (c *Local) PurgeExpired() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	n := 0
+	for e := c.ll.Front(); e != nil; {
+		next := e.Next()
+		if entry := e.Value.(*localEntry); !entry.expiresAt.IsZero() && !now.Before(entry.expiresAt) {
+			c.remove(e)
+			n++
+		}
+		e = next
+	}
+	return n
+}
+
+// lookup returns the live element for key and marks it recently used.
+// Expired entries are dropped on access.
+func (c *Local) lookup(key string) *list.Element {
+	e, ok := c.items[key]
+	if !ok {
+		return nil
+	}
+	entry := e.Value.(*localEntry)
+	if !entry.expiresAt.IsZero() && !c.now().Before(entry.expiresAt) {
+		c.remove(e)
+		return nil
+	}
+	c.ll.MoveToFront(e)
+	return e
+}
+
+func (c *Local) store(key, value string, ttl time.Duration) {
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = c.now().Add(ttl)
+	}
+
+	if e, ok := c.items[key]; ok {
+		entry := e.Value.(*localEntry)
+		entry.value, entry.expiresAt = value, expiresAt
+		c.ll.MoveToFront(e)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&localEntry{key: key, value: value, expiresAt: expiresAt})
+	for c.ll.Len() > c.maxEntries {
+		c.remove(c.ll.Back())
+	}
+}
+
+func (c *Local) remove(e *list.Element) {
+	c.ll.Remove(e)
+	delete(c.items, e.Value.(*localEntry).key)
+}
@@ -2,64 +2,88 @@ package cache
 
 import (
 	"context"
-	"log"
+	"errors"
+	"log/slog"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
-var (
-	Rdb *redis.Client
-	Ctx = context.Background()
-)
+const pingTimeout = 3 * time.Second
 
-func InitRedis(addr, password string, db int) {
-	Rdb = redis.NewClient(&redis.Options{
+// NewRedisClient creates a Redis client and checks that the server answers.
+// An unreachable server is logged but not fatal: go-redis reconnects on
+// demand, and callers treat cache errors as misses in the meantime.
+func NewRedisClient(addr, password string, db, poolSize int) *redis.Client {
+	rdb := redis.NewClient(&redis.Options{
 		Addr:     addr,
 		Password: password,
 		DB:       db,
+		PoolSize: poolSize,
 	})
 
-	if err := Rdb.Ping(Ctx).Err(); err != nil {
-		log.Fatalf("❌ Failed to connect to Redis: %v", err)
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		slog.WarnContext(ctx, "⚠️ Redis unreachable, continuing without it for now", "addr", addr, "err", err)
+		return rdb
 	}
 
-	log.Println("✅ Connected to Redis")
+	slog.InfoContext(ctx, "✅ Connected to Redis", "addr", addr)
+	return rdb
 }
 
-// ✅ new getter used by middlewares
-func Client() *redis.Client {
-	return Rdb
+// Redis is a Cache backed by a Redis server shared by all replicas.
+type Redis struct {
+	rdb     *redis.Client
+	breaker *Breaker
 }
 
-// Helper functions
-func Set(key string, value string, ttl time.Duration) {
-	if err := Rdb.Set(Ctx, key, value, ttl).Err(); err != nil {
-		log.Printf("❌ Redis SET error: %v", err)
-	}
+// NewRedis wraps rdb. breaker, if not nil, must be installed on rdb with
+// AddHook; errors it produces while open are not logged again.
+func NewRedis(rdb *redis.Client, breaker *Breaker) *Redis {
+	return &Redis{rdb: rdb, breaker: breaker}
 }
 
-func Get(key string) (string, bool) {
-	val, err := Rdb.Get(Ctx, key).Result()
-	if err == redis.Nil {
+func (c *Redis) Available() bool {
+	return c.breaker == nil || c.breaker.Available()
+}
+
+func (c *Redis) Get(ctx context.Context, key string) (string, bool) {
+	val, err := c.rdb.Get(ctx, key).Result()
+	if err == redis.Nil || errors.Is(err, ErrUnavailable) {
 		return "", false
 	}
 	if err != nil {
-		log.Printf("❌ Redis GET error: %v", err)
+		slog.ErrorContext(ctx, "❌ Redis GET error", "err", err)
 		return "", false
 	}
 	return val, true
 }
 
-// Delete removes a key from Redis
-func Delete(key string) {
-	ctx := context.Background()
-	if Rdb == nil {
-		log.Println("⚠️ Redis not initialized — cannot delete key:", key)
+func (c *Redis) Set(ctx context.Context, key, value string, ttl time.Duration) {
+	if err := c.rdb.Set(ctx, key, value, ttl).Err(); err != nil && !errors.Is(err, ErrUnavailable) {
+		slog.ErrorContext(ctx, "❌ Redis SET error", "err", err)
+	}
+}
+
+// Delete removes keys from Redis
+func (c *Redis) Delete(ctx context.Context, keys ...string) {
+	if len(keys) == 0 {
 		return
 	}
+	if err := c.rdb.Del(ctx, keys...).Err(); err != nil && !errors.Is(err, ErrUnavailable) {
+		slog.ErrorContext(ctx, "❌ Failed to delete cache keys", "keys", keys, "err", err)
+	}
+}
 
-	if err := Rdb.Del(ctx, key).Err(); err != nil {
-		log.Printf("❌ Failed to delete cache key %s: %v", key, err)
+func (c *Redis) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	// SETNX creates the counter with its TTL; later increments keep the TTL.
+	pipe := c.rdb.TxPipeline()
+	pipe.SetNX(ctx, key, 0, ttl)
+	incr := pipe.Incr(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
 	}
+	return incr.Val(), nil
 }
@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+const defaultL1TTL = 30 * time.Second
+
+// TwoTier puts an in-process L1 in front of a shared L2 (normally Redis).
+// Reads hit L1 first; writes and deletes go to both tiers.
+//
+// Deletes only reach the L1 of the node that issued them, so other nodes
+// may serve a stale value for up to l1TTL. Counters (Incr) always use L2 so
+// rate limits stay global.
+type TwoTier struct {
+	l1    Cache
+	l2    Cache
+	l1TTL time.Duration
+}
+
+// NewTwoTier combines l1 and l2; l1TTL caps how long L1 keeps a value (default 30s).
+func NewTwoTier(l1, l2 Cache, l1TTL time.Duration) *TwoTier {
+	if l1TTL <= 0 {
+		l1TTL = defaultL1TTL
+	}
+	return &TwoTier{l1: l1, l2: l2, l1TTL: l1TTL}
+}
+
+func (c *TwoTier) Get(ctx context.Context, key string) (string, bool) {
+	if v, ok := c.l1.Get(ctx, key); ok {
+		return v, true
+	}
+	v, ok := c.l2.Get(ctx, key)
+	if ok {
+		c.l1.Set(ctx, key, v, c.l1TTL)
+	}
+	return v, ok
+}
+
+func (c *TwoTier) Set(ctx context.Context, key, value string, ttl time.Duration) {
+	c.l2.Set(ctx, key, value, ttl)
+	c.l1.Set(ctx, key, value, c.localTTL(ttl))
+}
+
+func (c *TwoTier) Delete(ctx context.Context, keys ...string) {
+	c.l2.Delete(ctx, keys...)
+	c.l1.Delete(ctx, keys...)
+}
+
+func (c *TwoTier) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return c.l2.Incr(ctx, key, ttl)
+}
+
+func (c *TwoTier) localTTL(ttl time.Duration) time.Duration {
+	if ttl > 0 && ttl < c.l1TTL {
+		return ttl
+	}
+	return c.l1TTL
+}
+
+// PurgeExpired evicts expired keys from L1; L2 expires its own.
+func (c *TwoTier) PurgeExpired() int {
+	return PurgeExpired(c.l1)
+}
+
+// Available follows L2: while it is down only values already in L1 are served.
+func (c *TwoTier) Available() bool {
+	return Available(c.l2)
+}
@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/brij-812/HyperLinkOS/internal/tracing"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedCache starts a span around every call to the wrapped Cache.
+type tracedCache struct {
+	c    Cache
+	mode string
+}
+
+// Traced wraps c so each Get, Set, Delete and Incr gets its own span, tagged
+// with mode (redis, local, two_tier) and the key's prefix up to the first
+// colon; full keys carry short codes and client IPs, so they are left out.
+func Traced(c Cache, mode string) Cache {
+	return &tracedCache{c: c, mode: mode}
+}
+
+func (t *tracedCache) start(ctx context.Context, op, key string) (context.Context, trace.Span) {
+	prefix, _, _ := strings.Cut(key, ":")
+	return tracing.Tracer().Start(ctx, "cache "+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("cache.mode", t.mode),
+			attribute.String("cache.key_prefix", prefix),
+		),
+	)
+}
+
+func (t *tracedCache) Get(ctx context.Context, key string) (string, bool) {
+	ctx, span := t.start(ctx, "get", key)
+	defer span.End()
+	v, ok := t.c.Get(ctx, key)
+	span.SetAttributes(attribute.Bool("cache.hit", ok))
+	return v, ok
+}
+
+func (t *tracedCache) Set(ctx context.Context, key, value string, ttl time.Duration) {
+	ctx, span := t.start(ctx, "set", key)
+	defer span.End()
+	t.c.Set(ctx, key, value, ttl)
+}
+
+func (t *tracedCache) Delete(ctx context.Context, keys ...string) {
+	var first string
+	if len(keys) > 0 {
+		first = keys[0]
+	}
+	ctx, span := t.start(ctx, "delete", first)
+	defer span.End()
+	span.SetAttributes(attribute.Int("cache.keys", len(keys)))
+	t.c.Delete(ctx, keys...)
+}
+
+func (t *tracedCache) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	ctx, span := t.start(ctx, "incr", key)
+	n, err := t.c.Incr(ctx, key, ttl)
+	tracing.End(span, err)
+	return n, err
+}
+
+func (t *tracedCache) Available() bool {
+	return Available(t.c)
+}
+
+func (t *tracedCache) PurgeExpired() int {
+	return PurgeExpired(t.c)
+}
+
+// TracingHook gives every Redis command its own client span, including
+// those from rate limiting and the click stream, which bypass Cache.
+// Install it with rdb.AddHook.
+type TracingHook struct{}
+
+func (TracingHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (TracingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, span := startRedis(ctx, strings.ToUpper(cmd.Name()), 1)
+		err := next(ctx, cmd)
+		endRedis(span, err)
+		return err
+	}
+}
+
+func (TracingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		ctx, span := startRedis(ctx, "PIPELINE", len(cmds))
+		err := next(ctx, cmds)
+		endRedis(span, err)
+		return err
+	}
+}
+
+func startRedis(ctx context.Context, op string, n int) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{semconv.DBSystemNameRedis, semconv.DBOperationName(op)}
+	if n > 1 {
+		attrs = append(attrs, attribute.Int("db.operation.batch.size", n))
+	}
+	return tracing.Tracer().Start(ctx, "redis "+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// endRedis ends span; a missing key (redis.Nil) is a normal reply.
+func endRedis(span trace.Span, err error) {
+	if errors.Is(err, redis.Nil) {
+		err = nil
+	}
+	tracing.End(span, err)
+}
@@ -0,0 +1,128 @@
+// Package certs serves TLS certificates obtained over ACME for the
+// shortener's own hosts and its verified custom domains.
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/brij-812/HyperLinkOS/internal/repository"
+	"github.com/brij-812/HyperLinkOS/internal/utils"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Store persists ACME account keys and issued certificates. Any
+// autocert.Cache works; NewDirStore and NewPostgresStore are built in.
+type Store = autocert.Cache
+
+// NewDirStore keeps certificates as files in dir, which is created on first use.
+func NewDirStore(dir string) Store {
+	return autocert.DirCache(dir)
+}
+
+// HostResolver maps a verified custom domain to its ID; repository.DomainStore satisfies it.
+type HostResolver interface {
+	ResolveHost(ctx context.Context, hostname string) (int, error)
+}
+
+// ErrUnknownHost is returned by HostPolicy for hosts the shortener doesn't serve.
+var ErrUnknownHost = errors.New("host not served by this shortener")
+
+// HostPolicy only lets certificates be issued for hosts, plus any custom
+// domain whose ownership has been verified. Everything else is refused
+// before an ACME order is placed, so random SNI names can't burn rate limits.
+func HostPolicy(hosts []string, domains HostResolver) autocert.HostPolicy {
+	allowed := make(map[string]bool, len(hosts))
+	for _, h := range hosts {
+		if h = utils.NormalizeHost(h); h != "" {
+			allowed[h] = true
+		}
+	}
+	return func(ctx context.Context, host string) error {
+		host = utils.NormalizeHost(host)
+		if allowed[host] {
+			return nil
+		}
+		if domains == nil {
+			return fmt.Errorf("%w: %s", ErrUnknownHost, host)
+		}
+		_, err := domains.ResolveHost(ctx, host)
+		if errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("%w: %s", ErrUnknownHost, host)
+		}
+		return err
+	}
+}
+
+// ManagerConfig configures the ACME client; zero values use Let's Encrypt.
+type ManagerConfig struct {
+	// DirectoryURL is the ACME directory, e.g. a local Pebble for testing.
+	DirectoryURL string
+	// CAFile is a PEM bundle trusted for the directory's own HTTPS endpoint,
+	// needed for test CAs such as Pebble whose API uses a private root.
+	CAFile      string
+	Email       string
+	RenewBefore time.Duration
+}
+
+// NewManager returns an autocert.Manager that stores certificates in store
+// and only issues for hosts accepted by policy.
+func NewManager(cfg ManagerConfig, store Store, policy autocert.HostPolicy) (*autocert.Manager, error) {
+	m := &autocert.Manager{
+		Prompt:      autocert.AcceptTOS,
+		Cache:       store,
+		HostPolicy:  policy,
+		Email:       cfg.Email,
+		RenewBefore: cfg.RenewBefore,
+	}
+	if cfg.DirectoryURL == "" && cfg.CAFile == "" {
+		return m, nil
+	}
+
+	client := &acme.Client{DirectoryURL: cfg.DirectoryURL}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read ACME CA file: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in ACME CA file %s", cfg.CAFile)
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+		client.HTTPClient = &http.Client{Transport: transport}
+	}
+	m.Client = client
+	return m, nil
+}
+
+// RedirectHTTPS sends plain HTTP GET and HEAD requests to the same URL over
+// HTTPS on httpsPort; other methods are refused rather than replayed.
+func RedirectHTTPS(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "use HTTPS", http.StatusBadRequest)
+			return
+		}
+		host := utils.NormalizeHost(r.Host)
+		if host == "" {
+			http.Error(w, "missing host", http.StatusBadRequest)
+			return
+		}
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		} else if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+			host = "[" + host + "]" // bare IPv6 literal
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
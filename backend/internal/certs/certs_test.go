@@ -0,0 +1,114 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/brij-812/HyperLinkOS/internal/repository"
+)
+
+// fakeDomains resolves the verified custom domains in its map.
+type fakeDomains map[string]int
+
+func (d fakeDomains) ResolveHost(_ context.Context, hostname string) (int, error) {
+	if id, ok := d[hostname]; ok {
+		return id, nil
+	}
+	if hostname == "down.example" {
+		return 0, repository.ErrUnavailable
+	}
+	return 0, repository.ErrNotFound
+}
+
+func TestHostPolicy(t *testing.T) {
+	policy := HostPolicy([]string{"Sho.rt", ""}, fakeDomains{"go.acme.com": 7})
+
+	tests := []struct {
+		host    string
+		wantErr error
+	}{
+		{"sho.rt", nil},
+		{"SHO.RT.", nil},
+		{"go.acme.com", nil},
+		{"evil.example", ErrUnknownHost},
+		{"", ErrUnknownHost},
+		{"down.example", repository.ErrUnavailable},
+	}
+	for _, tt := range tests {
+		err := policy(context.Background(), tt.host)
+		if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+			t.Errorf("policy(%q) = %v, want %v", tt.host, err, tt.wantErr)
+		}
+	}
+
+	if err := HostPolicy(nil, nil)(context.Background(), "go.acme.com"); !errors.Is(err, ErrUnknownHost) {
+		t.Errorf("expected custom domains to be refused without a resolver, got %v", err)
+	}
+}
+
+func TestRedirectHTTPS(t *testing.T) {
+	tests := []struct {
+		port, method, target string
+		wantStatus           int
+		wantLocation         string
+	}{
+		{"443", http.MethodGet, "http://sho.rt/abc?x=1", http.StatusMovedPermanently, "https://sho.rt/abc?x=1"},
+		{"8443", http.MethodHead, "http://sho.rt:8080/abc", http.StatusMovedPermanently, "https://sho.rt:8443/abc"},
+		{"443", http.MethodGet, "http://[::1]:8080/abc", http.StatusMovedPermanently, "https://[::1]/abc"},
+		{"443", http.MethodPost, "http://sho.rt/shorten", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		RedirectHTTPS(tt.port).ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+		if rec.Code != tt.wantStatus || rec.Header().Get("Location") != tt.wantLocation {
+			t.Errorf("%s %s -> %d %q, want %d %q", tt.method, tt.target,
+				rec.Code, rec.Header().Get("Location"), tt.wantStatus, tt.wantLocation)
+		}
+	}
+}
+
+// TestPebbleIssuance orders a real certificate from a local Pebble, e.g.
+//
+//	docker run -e PEBBLE_VA_ALWAYS_VALID=1 -p 14000:14000 ghcr.io/letsencrypt/pebble
+//	PEBBLE_DIRECTORY_URL=https://localhost:14000/dir PEBBLE_CA_FILE=pebble.minica.pem go test ./internal/certs
+//
+// PEBBLE_CA_FILE is the root of Pebble's own HTTPS API (test/certs/pebble.minica.pem in its repo).
+func TestPebbleIssuance(t *testing.T) {
+	directory := os.Getenv("PEBBLE_DIRECTORY_URL")
+	if directory == "" {
+		t.Skip("PEBBLE_DIRECTORY_URL not set")
+	}
+
+	m, err := NewManager(ManagerConfig{
+		DirectoryURL: directory,
+		CAFile:       os.Getenv("PEBBLE_CA_FILE"),
+		Email:        "ops@sho.rt",
+	}, NewDirStore(t.TempDir()), HostPolicy([]string{"sho.rt"}, fakeDomains{"go.acme.com": 7}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, host := range []string{"sho.rt", "go.acme.com"} {
+		cert, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: host})
+		if err != nil {
+			t.Fatalf("GetCertificate(%s): %v", host, err)
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := leaf.VerifyHostname(host); err != nil {
+			t.Errorf("certificate for %s: %v", host, err)
+		}
+	}
+
+	if _, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "evil.example"}); err == nil {
+		t.Error("expected no certificate for an unknown host")
+	}
+}
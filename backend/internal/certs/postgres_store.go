@@ -0,0 +1,42 @@
+package certs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// PostgresStore keeps certificates in the acme_cache table so every
+// replica serves the same certificates and only one of them has to order.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Get(ctx context.Context, key string) ([]byte, error) {
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `SELECT data FROM acme_cache WHERE key = $1`, key).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, autocert.ErrCacheMiss
+	}
+	return data, err
+}
+
+func (s *PostgresStore) Put(ctx context.Context, key string, data []byte) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO acme_cache (key, data, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (key) DO UPDATE SET data = EXCLUDED.data, updated_at = NOW()
+	`, key, data)
+	return err
+}
+
+func (s *PostgresStore) Delete(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM acme_cache WHERE key = $1`, key)
+	return err
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"log"
+	"log/slog"
 	"strings"
 
 	"github.com/knadh/koanf/parsers/yaml"
@@ -15,9 +16,43 @@ var k = koanf.New(".")
 type Config struct {
 	Server struct {
 		Port string `koanf:"port"`
+		// PublicBaseURL is the origin short links are issued on (default http://localhost:8080).
+		PublicBaseURL string `koanf:"public_base_url"`
+		// TrustedProxies lists the CIDRs (or single IPs) allowed to report the client address.
+		TrustedProxies []string `koanf:"trusted_proxies"`
+		ClientIPHeader string   `koanf:"client_ip_header"` // X-Forwarded-For (default) | Forwarded | X-Real-IP
+
+		// HTTP server timeouts in seconds; 0 uses the default and a negative value disables the timeout.
+		ReadTimeoutSeconds       int `koanf:"read_timeout_seconds"`        // default 15
+		ReadHeaderTimeoutSeconds int `koanf:"read_header_timeout_seconds"` // default 5
+		WriteTimeoutSeconds      int `koanf:"write_timeout_seconds"`       // default 30
+		IdleTimeoutSeconds       int `koanf:"idle_timeout_seconds"`        // default 120
+		// ShutdownTimeoutSeconds bounds how long in-flight requests may drain on SIGTERM (default 30).
+		ShutdownTimeoutSeconds int `koanf:"shutdown_timeout_seconds"`
+
+		// TLS makes the server terminate HTTPS itself on Port.
+		TLS struct {
+			Mode     string `koanf:"mode"` // "" (plain HTTP, default) | static | acme
+			CertFile string `koanf:"cert_file"`
+			KeyFile  string `koanf:"key_file"`
+			// HTTPPort serves ACME http-01 challenges and redirects plain HTTP to
+			// HTTPS (acme default 80; static only when set).
+			HTTPPort string `koanf:"http_port"`
+			ACME     struct {
+				DirectoryURL string `koanf:"directory_url"` // default Let's Encrypt; point at Pebble to test
+				CAFile       string `koanf:"ca_file"`       // extra root for the directory's HTTPS, e.g. Pebble's
+				Email        string `koanf:"email"`
+				Store        string `koanf:"store"` // file (default) | postgres
+				Dir          string `koanf:"dir"`   // file store directory, default acme-certs
+				// Hosts are always allowed besides the public_base_url host and verified custom domains.
+				Hosts []string `koanf:"hosts"`
+			} `koanf:"acme"`
+		} `koanf:"tls"`
 	} `koanf:"server"`
 
 	Database struct {
+		Driver   string `koanf:"driver"` // postgres (default) | sqlite
+		Path     string `koanf:"path"`   // sqlite only: database file
 		Host     string `koanf:"host"`
 		Port     string `koanf:"port"`
 		User     string `koanf:"user"`
@@ -32,8 +67,125 @@ type Config struct {
 		Password string `koanf:"password"`
 		DB       int    `koanf:"db"`
 		PoolSize int    `koanf:"pool_size"`
+
+		// Circuit breaker: opens after FailureThreshold consecutive errors,
+		// then probes after OpenSeconds, doubling up to MaxOpenSeconds.
+		Breaker struct {
+			FailureThreshold int `koanf:"failure_threshold"`
+			OpenSeconds      int `koanf:"open_seconds"`
+			MaxOpenSeconds   int `koanf:"max_open_seconds"`
+		} `koanf:"breaker"`
 	} `koanf:"redis"`
 
+	Cache struct {
+		Mode            string `koanf:"mode"`              // redis (default) | local | two_tier | none
+		LocalMaxEntries int    `koanf:"local_max_entries"` // local and two_tier
+		L1TTLSeconds    int    `koanf:"l1_ttl_seconds"`    // two_tier: how long a node keeps its local copy
+
+		// Behaviour while Redis is down: open | closed (| local for rate_limit)
+		OnOutage struct {
+			RateLimit string `koanf:"rate_limit"` // default local
+			Redirects string `koanf:"redirects"`  // default open
+			Metrics   string `koanf:"metrics"`    // default open
+		} `koanf:"on_outage"`
+	} `koanf:"cache"`
+
+	RateLimit struct {
+		// Groups maps a route group (auth, api, shorten, redirect) to its limits.
+		// Groups left out keep the built-in limits for /shorten and redirects.
+		Groups map[string]RateLimitGroup `koanf:"groups"`
+
+		// Enumeration blocks IPs whose redirects mostly hit unknown or expired codes.
+		Enumeration struct {
+			Disabled      bool    `koanf:"disabled"`
+			WindowSeconds int     `koanf:"window_seconds"` // default 60
+			MinMisses     int     `koanf:"min_misses"`     // default 20
+			MissRatio     float64 `koanf:"miss_ratio"`     // default 0.5
+			BlockSeconds  int     `koanf:"block_seconds"`  // default 900
+		} `koanf:"enumeration"`
+	} `koanf:"rate_limit"`
+
+	Shortener struct {
+		Strategy   string `koanf:"strategy"` // hash | random | sequence | snowflake
+		Length     int    `koanf:"length"`   // default 6; must be unset for snowflake
+		MaxRetries int    `koanf:"max_retries"`
+		NodeID     int64  `koanf:"node_id"` // snowflake only; must differ per replica
+		// UnlockTTLMinutes is how long an entered link password is remembered (default 30).
+		UnlockTTLMinutes int `koanf:"unlock_ttl_minutes"`
+
+		// Pending is the answer for scheduled links before their activates_at:
+		// a redirect to FallbackURL if set, else Status (default 404) and Message.
+		Pending struct {
+			FallbackURL string `koanf:"fallback_url"`
+			Status      int    `koanf:"status"`
+			Message     string `koanf:"message"`
+		} `koanf:"pending"`
+	} `koanf:"shortener"`
+
+	Analytics struct {
+		IPHashSalt string `koanf:"ip_hash_salt"`
+		// Sink selects where the in-process batch writer flushes clicks:
+		// "buffer" (the database, default) or "stream" (Redis stream +
+		// consumer group). Either way redirects never wait on the write.
+		Sink                 string `koanf:"sink"`
+		BufferSize           int    `koanf:"buffer_size"`
+		BatchSize            int    `koanf:"batch_size"`
+		FlushIntervalSeconds int    `koanf:"flush_interval_seconds"`
+
+		Stream struct {
+			Key              string `koanf:"key"`
+			MaxLen           int64  `koanf:"max_len"`
+			ConsumerEnabled  bool   `koanf:"consumer_enabled"`
+			Group            string `koanf:"group"`
+			Consumer         string `koanf:"consumer"` // defaults to the hostname
+			BatchSize        int64  `koanf:"batch_size"`
+			BlockSeconds     int    `koanf:"block_seconds"`
+			ClaimIdleSeconds int    `koanf:"claim_idle_seconds"`
+		} `koanf:"stream"`
+	} `koanf:"analytics"`
+
+	// Scheduler runs periodic background jobs. An interval of 0 uses the
+	// job's default and a negative one disables it.
+	Scheduler struct {
+		Disabled    bool `koanf:"disabled"`
+		ExpirySweep struct {
+			IntervalSeconds int `koanf:"interval_seconds"` // default 300
+			BatchSize       int `koanf:"batch_size"`       // default 500
+		} `koanf:"expiry_sweep"`
+		CacheEviction struct {
+			IntervalSeconds int `koanf:"interval_seconds"` // default 60; in-process caches only
+		} `koanf:"cache_eviction"`
+		ClickRollup struct {
+			IntervalSeconds int `koanf:"interval_seconds"` // default 900; Postgres only
+			Days            int `koanf:"days"`             // recent days recomputed per run, default 2
+		} `koanf:"click_rollup"`
+	} `koanf:"scheduler"`
+
+	Log struct {
+		Level  string `koanf:"level"`  // debug | info (default) | warn | error
+		Format string `koanf:"format"` // json (default) | text
+	} `koanf:"log"`
+
+	// Metrics exposes Prometheus metrics, on the public router at Path unless
+	// Addr moves them to a separate admin listener (e.g. 127.0.0.1:9090).
+	Metrics struct {
+		Disabled bool   `koanf:"disabled"`
+		Path     string `koanf:"path"` // default /metrics/prometheus
+		Addr     string `koanf:"addr"`
+	} `koanf:"metrics"`
+
+	// Tracing exports OpenTelemetry spans for requests, Postgres queries and
+	// cache calls. Endpoint is an OTLP/HTTP traces URL such as
+	// http://otel-collector:4318/v1/traces; empty falls back to the standard
+	// OTEL_EXPORTER_OTLP_* variables.
+	Tracing struct {
+		Exporter    string            `koanf:"exporter"` // none (default) | stdout | otlp
+		Endpoint    string            `koanf:"endpoint"`
+		Headers     map[string]string `koanf:"headers"`      // e.g. an API key for a hosted backend
+		ServiceName string            `koanf:"service_name"` // default hyperlinkos
+		SampleRatio float64           `koanf:"sample_ratio"` // default 1; callers' sampling decisions win
+	} `koanf:"tracing"`
+
 	JWT struct {
 		Secret                   string `koanf:"secret"`
 		Issuer                   string `koanf:"issuer"`
@@ -42,9 +194,17 @@ type Config struct {
 	} `koanf:"jwt"`
 }
 
+// RateLimitGroup configures the limits of one route group.
+type RateLimitGroup struct {
+	Algorithm     string `koanf:"algorithm"`      // sliding_window (default) | token_bucket
+	WindowSeconds int    `koanf:"window_seconds"` // default 60
+	// Limits maps a principal kind (ip, user, api_key, plan:<name>) to requests per window.
+	Limits map[string]int `koanf:"limits"`
+}
+
 func LoadConfig() *Config {
 	if err := k.Load(file.Provider("config.yaml"), yaml.Parser()); err != nil {
-		log.Println("⚠️ No config.yaml found, skipping file load")
+		slog.Warn("⚠️ No config.yaml found, skipping file load", "err", err)
 	}
 
 	k.Load(env.Provider("", ".", func(s string) string {
@@ -4,11 +4,13 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 
 	"github.com/brij-812/HyperLinkOS/internal/config"
 	"github.com/golang-migrate/migrate/v4"
+	migratedb "github.com/golang-migrate/migrate/v4/database"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 )
@@ -21,22 +23,28 @@ func RunMigrations(db *sql.DB, cfg *config.Config, direction string) {
 		log.Fatalf("❌ Migration driver init failed: %v", err)
 	}
 
+	applyMigrations(driver, "migrations", cfg.Database.Name, direction)
+}
+
+// applyMigrations runs the migration set in dir (relative to the working
+// directory) against driver.
+func applyMigrations(driver migratedb.Driver, dir, databaseName, direction string) {
 	// Dynamically resolve absolute path to migrations folder
 	wd, err := os.Getwd()
 	if err != nil {
 		log.Fatalf("❌ Failed to get working directory: %v", err)
 	}
 
-	absPath := filepath.Join(wd, "migrations")
+	absPath := filepath.Join(wd, dir)
 
 	// On Windows, use file://C:/path instead of file:///C:/path
 	migrationPath := fmt.Sprintf("file://%s", filepath.ToSlash(absPath))
-	log.Printf("📂 Using migration path: %s", migrationPath)
+	slog.Info("📂 Using migration path", "path", migrationPath)
 
 	// Load migrations
 	m, err := migrate.NewWithDatabaseInstance(
 		migrationPath,
-		cfg.Database.Name,
+		databaseName,
 		driver,
 	)
 	if err != nil {
@@ -49,24 +57,24 @@ func RunMigrations(db *sql.DB, cfg *config.Config, direction string) {
 		if err := m.Up(); err != nil && err != migrate.ErrNoChange {
 			log.Fatalf("❌ Migration up failed: %v", err)
 		}
-		log.Println("✅ Migrations applied successfully")
+		slog.Info("✅ Migrations applied successfully")
 
 	case "down":
 		if err := m.Steps(-1); err != nil {
 			log.Fatalf("❌ Migration down failed: %v", err)
 		}
-		log.Println("⬅️  Rolled back one migration")
+		slog.Info("⬅️ Rolled back one migration")
 
 	case "version":
 		v, dirty, err := m.Version()
 		if err != nil {
 			if err == migrate.ErrNilVersion {
-				log.Println("ℹ️  No migrations applied yet")
+				slog.Info("ℹ️ No migrations applied yet")
 				return
 			}
 			log.Fatalf("❌ Failed to get migration version: %v", err)
 		}
-		log.Printf("📦 Current DB version: %d (dirty: %v)\n", v, dirty)
+		slog.Info("📦 Current DB version", "version", v, "dirty", dirty)
 
 	default:
 		log.Fatalf("❌ Invalid migration command: %s (use 'up', 'down', or 'version')", direction)
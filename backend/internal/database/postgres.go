@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"log/slog"
 
 	"github.com/brij-812/HyperLinkOS/internal/config"
 	_ "github.com/lib/pq" // Postgres driver
@@ -29,7 +30,7 @@ func NewPostgresDB(cfg *config.Config) *sql.DB {
 		log.Fatalf("❌ Failed to ping Postgres: %v", err)
 	}
 
-	log.Println("✅ Connected to Postgres successfully")
+	slog.Info("✅ Connected to Postgres successfully")
 
 	db.SetMaxOpenConns(25)
 	db.SetMaxIdleConns(25)
@@ -0,0 +1,48 @@
+//go:build sqlite
+
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"log/slog"
+
+	"github.com/brij-812/HyperLinkOS/internal/config"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	_ "modernc.org/sqlite" // pure-Go "sqlite" driver
+)
+
+// NewSQLiteDB opens (or creates) the SQLite database file at cfg.Database.Path.
+func NewSQLiteDB(cfg *config.Config) *sql.DB {
+	dsn := fmt.Sprintf(
+		"file:%s?_time_format=sqlite&_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)",
+		cfg.Database.Path,
+	)
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		log.Fatalf("❌ Failed to open SQLite database: %v", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		log.Fatalf("❌ Failed to open SQLite database %s: %v", cfg.Database.Path, err)
+	}
+
+	slog.Info("✅ Opened SQLite database", "path", cfg.Database.Path)
+
+	// SQLite allows a single writer; one connection avoids SQLITE_BUSY between our own queries.
+	db.SetMaxOpenConns(1)
+
+	return db
+}
+
+// RunSQLiteMigrations is RunMigrations for the SQLite migration set in migrations/sqlite.
+func RunSQLiteMigrations(db *sql.DB, cfg *config.Config, direction string) {
+	driver, err := sqlite.WithInstance(db, &sqlite.Config{})
+	if err != nil {
+		log.Fatalf("❌ Migration driver init failed: %v", err)
+	}
+
+	applyMigrations(driver, "migrations/sqlite", cfg.Database.Path, direction)
+}
@@ -0,0 +1,22 @@
+//go:build !sqlite
+
+package database
+
+import (
+	"database/sql"
+	"log"
+
+	"github.com/brij-812/HyperLinkOS/internal/config"
+)
+
+// The SQLite driver is only compiled in with -tags sqlite, keeping the
+// default Postgres build free of it.
+
+func NewSQLiteDB(cfg *config.Config) *sql.DB {
+	log.Fatalf("❌ database.driver=sqlite needs a binary built with -tags sqlite")
+	return nil
+}
+
+func RunSQLiteMigrations(db *sql.DB, cfg *config.Config, direction string) {
+	log.Fatalf("❌ database.driver=sqlite needs a binary built with -tags sqlite")
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brij-812/HyperLinkOS/internal/models"
+	"github.com/brij-812/HyperLinkOS/internal/repository"
+	"github.com/go-chi/chi/v5"
+)
+
+type APIKeyHandler struct {
+	Keys repository.APIKeyStore
+}
+
+func NewAPIKeyHandler(keys repository.APIKeyStore) *APIKeyHandler {
+	return &APIKeyHandler{Keys: keys}
+}
+
+// 🔹 POST /apikeys — the raw key is returned once and never stored
+func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(int)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "name field required", http.StatusBadRequest)
+		return
+	}
+
+	scopes := req.Scopes
+	if len(scopes) == 0 {
+		scopes = models.AllScopes
+	}
+	for _, s := range scopes {
+		if !slices.Contains(models.AllScopes, s) {
+			http.Error(w, "unknown scope "+s, http.StatusBadRequest)
+			return
+		}
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiryDays > 0 {
+		t := time.Now().Add(time.Duration(req.ExpiryDays) * 24 * time.Hour)
+		expiresAt = &t
+	}
+
+	key, raw, err := h.Keys.CreateAPIKey(r.Context(), userID, req.Name, scopes, expiresAt)
+	if err != nil {
+		writeRepoError(w, r, err, "api key not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(models.CreateAPIKeyResponse{APIKey: *key, Key: raw})
+}
+
+// 🔹 GET /apikeys
+func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(int)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	keys, err := h.Keys.ListAPIKeys(r.Context(), userID)
+	if err != nil {
+		writeRepoError(w, r, err, "api key not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+// 🔹 DELETE /apikeys/{id}
+func (h *APIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(int)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid api key id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Keys.RevokeAPIKey(r.Context(), userID, id); err != nil {
+		writeRepoError(w, r, err, "api key not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "api key revoked"})
+}
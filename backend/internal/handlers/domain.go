@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/brij-812/HyperLinkOS/internal/models"
+	"github.com/brij-812/HyperLinkOS/internal/repository"
+	"github.com/brij-812/HyperLinkOS/internal/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+// Ownership is proven by publishing
+//
+//	_hyperlinkos.<hostname>  TXT  "hyperlinkos-verify=<token>"
+const (
+	txtRecordPrefix = "_hyperlinkos."
+	txtValuePrefix  = "hyperlinkos-verify="
+)
+
+type DomainHandler struct {
+	Domains repository.DomainStore
+	// LookupTXT resolves TXT records; replaceable in tests.
+	LookupTXT func(ctx context.Context, name string) ([]string, error)
+}
+
+func NewDomainHandler(domains repository.DomainStore) *DomainHandler {
+	return &DomainHandler{Domains: domains, LookupTXT: net.DefaultResolver.LookupTXT}
+}
+
+func domainResponse(d models.Domain) models.DomainResponse {
+	return models.DomainResponse{
+		Domain:         d,
+		TXTRecordName:  txtRecordPrefix + d.Hostname,
+		TXTRecordValue: txtValuePrefix + d.VerificationToken,
+	}
+}
+
+// 🔹 POST /domains — registers a hostname and returns the TXT record to publish
+func (h *DomainHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(int)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CreateDomainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	hostname, err := utils.ValidateHostname(req.Hostname)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	token, err := randomToken(24)
+	if err != nil {
+		http.Error(w, "failed to create verification token", http.StatusInternalServerError)
+		return
+	}
+
+	d, err := h.Domains.CreateDomain(r.Context(), userID, hostname, token)
+	if errors.Is(err, repository.ErrDomainTaken) {
+		http.Error(w, "domain already registered", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		writeRepoError(w, r, err, "domain not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(domainResponse(*d))
+}
+
+// 🔹 GET /domains
+func (h *DomainHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(int)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	domains, err := h.Domains.ListDomains(r.Context(), userID)
+	if err != nil {
+		writeRepoError(w, r, err, "domain not found")
+		return
+	}
+
+	resp := make([]models.DomainResponse, 0, len(domains))
+	for _, d := range domains {
+		resp = append(resp, domainResponse(d))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// 🔹 POST /domains/{id}/verify — checks the TXT record and marks the domain verified
+func (h *DomainHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(int)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid domain id", http.StatusBadRequest)
+		return
+	}
+
+	d, err := h.Domains.GetDomain(r.Context(), userID, id)
+	if err != nil {
+		writeRepoError(w, r, err, "domain not found")
+		return
+	}
+
+	if !d.Verified() {
+		records, err := h.LookupTXT(r.Context(), txtRecordPrefix+d.Hostname)
+		if err != nil {
+			slog.WarnContext(r.Context(), "⚠️ TXT lookup failed", "hostname", d.Hostname, "err", err)
+		}
+		if !slices.Contains(records, txtValuePrefix+d.VerificationToken) {
+			http.Error(w, "verification TXT record not found", http.StatusBadRequest)
+			return
+		}
+		err = h.Domains.MarkDomainVerified(r.Context(), userID, id)
+		if errors.Is(err, repository.ErrDomainTaken) {
+			http.Error(w, "domain already verified by another account", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			writeRepoError(w, r, err, "domain not found")
+			return
+		}
+		if d, err = h.Domains.GetDomain(r.Context(), userID, id); err != nil {
+			writeRepoError(w, r, err, "domain not found")
+			return
+		}
+		slog.InfoContext(r.Context(), "✅ Domain verified", "hostname", d.Hostname, "user_id", userID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(domainResponse(*d))
+}
+
+// 🔹 DELETE /domains/{id} — links on the domain fall back to the default domain
+func (h *DomainHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(int)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid domain id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Domains.DeleteDomain(r.Context(), userID, id); err != nil {
+		writeRepoError(w, r, err, "domain not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "domain deleted"})
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/brij-812/HyperLinkOS/internal/repository"
+)
+
+// writeRepoError maps a repository error onto an HTTP status. notFound is the
+// message used for ErrNotFound so each endpoint keeps its own wording.
+func writeRepoError(w http.ResponseWriter, r *http.Request, err error, notFound string) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, notFound, http.StatusNotFound)
+	case errors.Is(err, repository.ErrExpired):
+		http.Error(w, "short URL expired", http.StatusGone)
+	case errors.Is(err, repository.ErrExhausted):
+		http.Error(w, "short URL has reached its click limit", http.StatusGone)
+	case errors.Is(err, repository.ErrExpiryBeforeActivation):
+		http.Error(w, "expires_at must be after activates_at", http.StatusBadRequest)
+	case errors.Is(err, repository.ErrConflict):
+		http.Error(w, "conflict", http.StatusConflict)
+	case errors.Is(err, repository.ErrUnavailable), errors.Is(err, context.DeadlineExceeded):
+		http.Error(w, "service temporarily unavailable", http.StatusServiceUnavailable)
+	case errors.Is(err, context.Canceled):
+		// the client went away; nobody is listening for the response
+		return
+	default:
+		slog.ErrorContext(r.Context(), "❌ Unexpected repository error", "err", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+}
@@ -1,24 +1,68 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/brij-812/HyperLinkOS/internal/analytics"
+	"github.com/brij-812/HyperLinkOS/internal/middleware"
 	"github.com/brij-812/HyperLinkOS/internal/models"
 	"github.com/brij-812/HyperLinkOS/internal/repository"
 	"github.com/brij-812/HyperLinkOS/internal/utils"
 	"github.com/go-chi/chi/v5"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// defaultMaxCodeRetries bounds how often a colliding generated code is retried.
+const defaultMaxCodeRetries = 5
+
+// defaultBaseURL is used for short links when no public base URL is configured.
+const defaultBaseURL = "http://localhost:8080"
+
 type URLHandler struct {
-	Repo repository.Repository
+	Repo       repository.Repository
+	Codes      utils.CodeGenerator
+	MaxRetries int
+
+	// BaseURL is the public origin short links are issued on, e.g. https://hl.example.com.
+	BaseURL string
+	// Domains resolves custom domains; nil serves every request on the default domain.
+	Domains repository.DomainStore
+
+	// Clicks receives an event for every successful redirect; nil disables tracking.
+	Clicks     analytics.Recorder
+	IPHashSalt string
+
+	// PendingFallbackURL receives visitors of scheduled links that aren't live
+	// yet; when empty they get PendingStatus (default 404) and PendingMessage.
+	PendingFallbackURL string
+	PendingStatus      int
+	PendingMessage     string
+
+	// UnlockSecret signs the cookie that remembers an unlocked password-protected
+	// link for UnlockTTL (default 30 minutes). Without it visitors are asked every time.
+	// Use UnlockKey rather than reusing another secret as is.
+	UnlockSecret []byte
+	UnlockTTL    time.Duration
 }
 
-func NewURLHandler(repo repository.Repository) *URLHandler {
-	return &URLHandler{Repo: repo}
+// NewURLHandler wires the handler; a nil generator falls back to the hash strategy.
+func NewURLHandler(repo repository.Repository, codes utils.CodeGenerator, maxRetries int) *URLHandler {
+	if codes == nil {
+		codes = &utils.HashGenerator{Length: utils.DefaultCodeLength}
+	}
+	if maxRetries <= 0 {
+		maxRetries = defaultMaxCodeRetries
+	}
+	return &URLHandler{Repo: repo, Codes: codes, MaxRetries: maxRetries}
 }
 
 func normalizeURL(raw string) string {
@@ -52,6 +96,11 @@ func (h *URLHandler) ShortenURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.MaxClicks < 0 {
+		http.Error(w, "max_clicks must not be negative", http.StatusBadRequest)
+		return
+	}
+
 	// 🕓 Handle optional expiry_days
 	var expiresAt *time.Time
 	if req.ExpiryDays > 0 {
@@ -59,22 +108,186 @@ func (h *URLHandler) ShortenURL(w http.ResponseWriter, r *http.Request) {
 		expiresAt = &t
 	}
 
-	code, exists := h.Repo.GetCode(req.URL)
-	if !exists {
-		code = utils.GenerateShortCode(req.URL)
-		// New Save signature includes expiry
-		h.Repo.Save(req.URL, code, userID, expiresAt)
-	} else {
-		h.Repo.IncrementDomainCount(req.URL, userID)
+	// 🚀 Optional launch time; expiry_days still counts from now
+	if req.ActivatesAt != nil && expiresAt != nil && !req.ActivatesAt.Before(*expiresAt) {
+		http.Error(w, "activates_at must be before the expiry", http.StatusBadRequest)
+		return
+	}
+
+	link := &models.Link{
+		LongURL:   req.URL,
+		UserID:    userID,
+		Shared:    req.Shared,
+		ExpiresAt: expiresAt,
+	}
+	if req.ActivatesAt != nil && req.ActivatesAt.After(time.Now()) {
+		link.ActivatesAt = req.ActivatesAt
+	}
+	// 🎟️ Optional click limit (1 = one-time link)
+	if req.MaxClicks > 0 {
+		link.ClicksLeft = &req.MaxClicks
+	}
+
+	// 🔒 Optional password, hashed like account passwords
+	if req.Password != "" {
+		if len(req.Password) > maxLinkPasswordBytes {
+			http.Error(w, fmt.Sprintf("password must be at most %d bytes", maxLinkPasswordBytes), http.StatusBadRequest)
+			return
+		}
+		hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			http.Error(w, "failed to hash password", http.StatusInternalServerError)
+			return
+		}
+		link.PasswordHash = string(hashed)
+	}
+
+	// 🌐 Optional custom domain: must be one of the caller's verified domains
+	if req.Domain != "" {
+		host := utils.NormalizeHost(req.Domain)
+		if h.Domains == nil {
+			http.Error(w, "custom domains are not enabled", http.StatusBadRequest)
+			return
+		}
+		d, err := h.Domains.GetVerifiedDomain(r.Context(), userID, host)
+		if errors.Is(err, repository.ErrNotFound) {
+			http.Error(w, "domain not found or not verified", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			writeRepoError(w, r, err, "domain not found")
+			return
+		}
+		link.DomainID = d.ID
+		link.Domain = d.Hostname
+	}
+
+	ctx := r.Context()
+	if req.Alias != "" {
+		// 🏷️ Custom alias: always a new link, never deduplicated
+		if err := utils.ValidateAlias(req.Alias); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		link.Code = req.Alias
+		if err := h.Repo.Save(ctx, link); err != nil {
+			if errors.Is(err, repository.ErrCodeTaken) {
+				http.Error(w, "alias already in use", http.StatusConflict)
+				return
+			}
+			writeRepoError(w, r, err, "link not found")
+			return
+		}
+	} else if code, err := h.ownCode(ctx, req, userID, link.DomainID); err == nil {
+		// 🔁 The user already owns a live link for this URL
+		link.Code = code
+		if err := h.Repo.IncrementDomainCount(ctx, req.URL, userID); err != nil {
+			slog.WarnContext(ctx, "⚠️ Domain count not bumped", "code", code, "err", err)
+		}
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		writeRepoError(w, r, err, "link not found")
+		return
+	} else if code, err := h.sharedCode(ctx, req); err == nil {
+		// 🤝 Opted in to reuse another user's shared link; it stays theirs
+		link.Code = code
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		writeRepoError(w, r, err, "link not found")
+		return
+	} else if err := h.saveGenerated(ctx, link); err != nil {
+		if errors.Is(err, repository.ErrCodeTaken) {
+			http.Error(w, "could not allocate a unique short code", http.StatusServiceUnavailable)
+			return
+		}
+		writeRepoError(w, r, err, "link not found")
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(models.ShortenResponse{
-		ShortURL:  "http://localhost:8080/" + code,
-		ExpiresAt: expiresAt,
+		ShortURL:    h.shortURL(link.Code, link.Domain),
+		ActivatesAt: link.ActivatesAt,
+		ExpiresAt:   expiresAt,
+		MaxClicks:   req.MaxClicks,
 	})
 }
 
+// shortURL builds the public link for a code, on its custom domain if it has one.
+// Custom domains are served with the same scheme as the base URL.
+func (h *URLHandler) shortURL(code, domain string) string {
+	base := strings.TrimRight(h.BaseURL, "/")
+	if base == "" {
+		base = defaultBaseURL
+	}
+	if domain != "" {
+		scheme := "https"
+		if u, err := url.Parse(base); err == nil && u.Scheme != "" {
+			scheme = u.Scheme
+		}
+		return scheme + "://" + domain + "/" + code
+	}
+	return base + "/" + code
+}
+
+// domainForHost maps the request Host to the domain whose codes it serves.
+// The base host and any unknown host serve the default domain (0).
+func (h *URLHandler) domainForHost(ctx context.Context, hostHeader string) (int, error) {
+	host := utils.NormalizeHost(hostHeader)
+	if h.Domains == nil || host == "" {
+		return 0, nil
+	}
+	base := h.BaseURL
+	if base == "" {
+		base = defaultBaseURL
+	}
+	if u, err := url.Parse(base); err == nil && utils.NormalizeHost(u.Host) == host {
+		return 0, nil
+	}
+	id, err := h.Domains.ResolveHost(ctx, host)
+	if errors.Is(err, repository.ErrNotFound) {
+		return 0, nil
+	}
+	return id, err
+}
+
+// ownCode looks up the caller's existing link for the URL. Password-protected,
+// click-limited and scheduled requests always get a new link of their own.
+func (h *URLHandler) ownCode(ctx context.Context, req models.ShortenRequest, userID, domainID int) (string, error) {
+	if req.Password != "" || req.MaxClicks > 0 || req.ActivatesAt != nil {
+		return "", repository.ErrNotFound
+	}
+	return h.Repo.GetCode(ctx, req.URL, userID, domainID)
+}
+
+// sharedCode looks up a shared link for the URL when the request opted in.
+// Shared links only exist on the default domain.
+func (h *URLHandler) sharedCode(ctx context.Context, req models.ShortenRequest) (string, error) {
+	if !req.ReuseShared || req.Domain != "" || req.Password != "" || req.MaxClicks > 0 || req.ActivatesAt != nil {
+		return "", repository.ErrNotFound
+	}
+	return h.Repo.GetSharedCode(ctx, req.URL)
+}
+
+// saveGenerated asks the code generator for candidates until Save accepts one,
+// giving up with ErrCodeTaken after MaxRetries collisions. On success link.Code is set.
+func (h *URLHandler) saveGenerated(ctx context.Context, link *models.Link) error {
+	for attempt := 0; attempt <= h.MaxRetries; attempt++ {
+		code, err := h.Codes.Generate(link.LongURL, attempt)
+		if err != nil {
+			return err
+		}
+		link.Code = code
+		err = h.Repo.Save(ctx, link)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, repository.ErrCodeTaken) {
+			return err
+		}
+		slog.WarnContext(ctx, "⚠️ Short code collision", "code", code, "attempt", attempt+1, "max_attempts", h.MaxRetries+1)
+	}
+	return repository.ErrCodeTaken
+}
+
 // 🔹 Redirect (Public)
 func (h *URLHandler) RedirectURL(w http.ResponseWriter, r *http.Request) {
 	shortCode := chi.URLParam(r, "shortCode")
@@ -83,16 +296,53 @@ func (h *URLHandler) RedirectURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	longURL, exists := h.Repo.GetURL(shortCode)
-	if !exists {
-		// Could be expired or not found
-		http.Error(w, "short URL expired or not found", http.StatusGone)
+	domainID, err := h.domainForHost(r.Context(), r.Host)
+	if err != nil {
+		writeRepoError(w, r, err, "short URL not found")
+		return
+	}
+	longURL, err := h.Repo.GetURL(r.Context(), shortCode, domainID)
+	if errors.Is(err, repository.ErrPasswordRequired) {
+		h.serveProtected(w, r, shortCode, domainID)
+		return
+	}
+	if err != nil {
+		h.writeRedirectError(w, r, err)
 		return
 	}
 
+	h.recordClick(r, shortCode)
 	http.Redirect(w, r, longURL, http.StatusFound)
 }
 
+// writeRedirectError answers a failed redirect. Scheduled links that aren't
+// live yet get the configured fallback instead of an error.
+func (h *URLHandler) writeRedirectError(w http.ResponseWriter, r *http.Request, err error) {
+	if !errors.Is(err, repository.ErrNotActive) {
+		writeRepoError(w, r, err, "short URL not found")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	if h.PendingFallbackURL != "" {
+		http.Redirect(w, r, h.PendingFallbackURL, http.StatusFound)
+		return
+	}
+	status, msg := h.PendingStatus, h.PendingMessage
+	if status == 0 {
+		status = http.StatusNotFound
+	}
+	if msg == "" {
+		msg = "short URL is not live yet"
+	}
+	http.Error(w, msg, status)
+}
+
+func (h *URLHandler) recordClick(r *http.Request, code string) {
+	if h.Clicks != nil {
+		h.Clicks.Record(r.Context(), analytics.NewClickEvent(r, code, middleware.ClientIP(r), h.IPHashSalt))
+	}
+}
+
 // 🔹 Metrics (Protected, per user)
 func (h *URLHandler) GetMetrics(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value("user_id").(int)
@@ -101,11 +351,70 @@ func (h *URLHandler) GetMetrics(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	data := h.Repo.GetTopDomains(userID, 3)
+	data, err := h.Repo.GetTopDomains(r.Context(), userID, 3)
+	if err != nil {
+		writeRepoError(w, r, err, "no metrics found")
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(data)
 }
 
+// Stats query limits: hourly series are capped tighter to keep responses small.
+const (
+	defaultStatsDays = 30
+	maxStatsDays     = 365
+	maxHourlyDays    = 14
+)
+
+// 🔹 Click analytics for one link (Protected, owner only)
+// GET /url/{code}/stats?bucket=hour|day&days=N
+func (h *URLHandler) GetLinkStats(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(int)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	code := chi.URLParam(r, "code")
+	if code == "" {
+		http.Error(w, "missing short code", http.StatusBadRequest)
+		return
+	}
+
+	bucket := r.URL.Query().Get("bucket")
+	if bucket == "" {
+		bucket = repository.BucketDay
+	}
+	if bucket != repository.BucketDay && bucket != repository.BucketHour {
+		http.Error(w, "bucket must be 'hour' or 'day'", http.StatusBadRequest)
+		return
+	}
+
+	days := defaultStatsDays
+	if raw := r.URL.Query().Get("days"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxStatsDays {
+			http.Error(w, fmt.Sprintf("days must be between 1 and %d", maxStatsDays), http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+	if bucket == repository.BucketHour && days > maxHourlyDays {
+		days = maxHourlyDays
+	}
+
+	since := time.Now().UTC().AddDate(0, 0, -days)
+	stats, err := h.Repo.GetLinkStats(r.Context(), userID, code, since, bucket)
+	if err != nil {
+		writeRepoError(w, r, err, "link not found or unauthorized")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
 // 🔹 Get all URLs created by the current user (Protected)
 func (h *URLHandler) GetAllUserURLs(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value("user_id").(int)
@@ -114,17 +423,107 @@ func (h *URLHandler) GetAllUserURLs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	urls := h.Repo.GetAllURLsByUser(userID)
+	links, err := h.Repo.GetAllURLsByUser(r.Context(), userID)
+	if err != nil {
+		writeRepoError(w, r, err, "no links found")
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 
-	if len(urls) == 0 {
+	if len(links) == 0 {
 		json.NewEncoder(w).Encode([]string{})
 		return
 	}
 
+	urls := make([]map[string]string, 0, len(links))
+	for _, l := range links {
+		expiry, activation := "", ""
+		if l.ExpiresAt != nil {
+			expiry = l.ExpiresAt.Format(time.RFC3339)
+		}
+		if l.ActivatesAt != nil {
+			activation = l.ActivatesAt.Format(time.RFC3339)
+		}
+		urls = append(urls, map[string]string{
+			"code":         l.Code,
+			"short_url":    h.shortURL(l.Code, l.Domain),
+			"long_url":     l.LongURL,
+			"created_at":   l.CreatedAt.Format(time.RFC3339),
+			"activates_at": activation,
+			"expires_at":   expiry,
+		})
+	}
 	json.NewEncoder(w).Encode(urls)
 }
 
+// 🔹 PATCH /url/{code} — edit destination, expiry or sharing of an owned link
+func (h *URLHandler) UpdateURL(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(int)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	code := chi.URLParam(r, "code")
+	if code == "" {
+		http.Error(w, "missing short code", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var upd models.LinkUpdate
+	if req.LongURL != nil {
+		u := normalizeURL(*req.LongURL)
+		if parsed, err := url.Parse(u); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			http.Error(w, "long_url must be an absolute URL", http.StatusBadRequest)
+			return
+		}
+		upd.LongURL = &u
+	}
+	upd.Shared = req.Shared
+
+	switch {
+	case req.ExpiresAt != nil && req.ExpiryDays != nil:
+		http.Error(w, "set either expires_at or expiry_days, not both", http.StatusBadRequest)
+		return
+	case req.ExpiresAt != nil:
+		if !req.ExpiresAt.After(time.Now()) {
+			http.Error(w, "expires_at must be in the future", http.StatusBadRequest)
+			return
+		}
+		upd.SetExpiry, upd.ExpiresAt = true, req.ExpiresAt
+	case req.ExpiryDays != nil:
+		if *req.ExpiryDays < 0 {
+			http.Error(w, "expiry_days must not be negative", http.StatusBadRequest)
+			return
+		}
+		upd.SetExpiry = true
+		if *req.ExpiryDays > 0 {
+			t := time.Now().Add(time.Duration(*req.ExpiryDays) * 24 * time.Hour)
+			upd.ExpiresAt = &t
+		}
+	}
+
+	if upd.LongURL == nil && upd.Shared == nil && !upd.SetExpiry {
+		http.Error(w, "nothing to update", http.StatusBadRequest)
+		return
+	}
+
+	link, err := h.Repo.UpdateLink(r.Context(), userID, code, upd)
+	if err != nil {
+		writeRepoError(w, r, err, "link not found or unauthorized")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(link)
+}
+
 func (h *URLHandler) DeleteURL(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value("user_id").(int)
 	if !ok {
@@ -138,8 +537,8 @@ func (h *URLHandler) DeleteURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if ok := h.Repo.DeleteLink(userID, code); !ok {
-		http.Error(w, "link not found or unauthorized", http.StatusNotFound)
+	if err := h.Repo.DeleteLink(r.Context(), userID, code); err != nil {
+		writeRepoError(w, r, err, "link not found or unauthorized")
 		return
 	}
 
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/brij-812/HyperLinkOS/internal/models"
+	"github.com/brij-812/HyperLinkOS/internal/repository"
+)
+
+const (
+	refreshCookieName = "hl_refresh"
+
+	defaultRefreshTokenExpiryHours = 24 * 7
+)
+
+var (
+	errRefreshInvalid = errors.New("invalid refresh token")
+	errRefreshExpired = errors.New("refresh token expired")
+	errRefreshReused  = errors.New("refresh token reuse detected")
+)
+
+func (h *UserHandler) refreshTTL() time.Duration {
+	hours := h.RefreshTokenExpiryHours
+	if hours <= 0 {
+		hours = defaultRefreshTokenExpiryHours
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+func (h *UserHandler) refreshTTLSeconds() int {
+	return int(h.refreshTTL() / time.Second)
+}
+
+// 🔹 POST /token/refresh
+// Exchanges a valid refresh token for a new access token and a rotated refresh token.
+func (h *UserHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	raw := refreshTokenFromRequest(r)
+	if raw == "" {
+		http.Error(w, "missing refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	userID, email, plan, newRefresh, err := h.rotateRefreshToken(r.Context(), raw)
+	switch {
+	case errors.Is(err, errRefreshInvalid), errors.Is(err, errRefreshExpired), errors.Is(err, errRefreshReused):
+		h.clearSessionCookies(w)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	case err != nil:
+		writeRepoError(w, r, err, "invalid refresh token")
+		return
+	}
+
+	accessToken, err := h.signAccessToken(userID, plan)
+	if err != nil {
+		http.Error(w, "failed to create token", http.StatusInternalServerError)
+		return
+	}
+
+	h.setSessionCookies(w, accessToken, newRefresh, email)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "token refreshed",
+	})
+}
+
+// refreshTokenFromRequest reads the refresh token from its cookie, falling
+// back to a JSON body {"refresh_token": "..."} for non-browser clients.
+func refreshTokenFromRequest(r *http.Request) string {
+	if cookie, err := r.Cookie(refreshCookieName); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+	return body.RefreshToken
+}
+
+// issueRefreshToken stores the hash of a new random token and returns the raw
+// value. An empty familyID starts a new family (i.e. a new login session).
+func (h *UserHandler) issueRefreshToken(ctx context.Context, userID int, familyID string) (string, error) {
+	raw, t, err := h.newRefreshToken(userID, familyID)
+	if err != nil {
+		return "", err
+	}
+	if err := h.Tokens.CreateRefreshToken(ctx, t); err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// newRefreshToken returns a random raw token and the record stored for it.
+func (h *UserHandler) newRefreshToken(userID int, familyID string) (string, *models.RefreshToken, error) {
+	raw, err := randomToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+	if familyID == "" {
+		if familyID, err = randomToken(16); err != nil {
+			return "", nil, err
+		}
+	}
+	return raw, &models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(raw),
+		ExpiresAt: time.Now().Add(h.refreshTTL()),
+	}, nil
+}
+
+// rotateRefreshToken revokes the presented token and issues its successor in
+// the same family. Presenting an already-revoked token means it was stolen
+// or replayed, so the whole family is revoked. Losing a race against a
+// concurrent rotation of the same token counts as reuse too.
+func (h *UserHandler) rotateRefreshToken(ctx context.Context, raw string) (userID int, email, plan, newRaw string, err error) {
+	t, err := h.Tokens.GetRefreshToken(ctx, hashToken(raw))
+	if errors.Is(err, repository.ErrNotFound) {
+		return 0, "", "", "", errRefreshInvalid
+	}
+	if err != nil {
+		return 0, "", "", "", err
+	}
+
+	if t.RevokedAt != nil {
+		return 0, "", "", "", h.refreshReused(ctx, t)
+	}
+	if time.Now().After(t.ExpiresAt) {
+		return 0, "", "", "", errRefreshExpired
+	}
+
+	newRaw, next, err := h.newRefreshToken(t.UserID, t.FamilyID)
+	if err != nil {
+		return 0, "", "", "", err
+	}
+	err = h.Tokens.RotateRefreshToken(ctx, t.ID, next)
+	if errors.Is(err, repository.ErrTokenRevoked) {
+		return 0, "", "", "", h.refreshReused(ctx, t)
+	}
+	if err != nil {
+		return 0, "", "", "", err
+	}
+	return t.UserID, t.Email, t.Plan, newRaw, nil
+}
+
+// refreshReused revokes the family of a replayed token and returns
+// errRefreshReused, or the storage error if the family couldn't be revoked.
+func (h *UserHandler) refreshReused(ctx context.Context, t *models.RefreshToken) error {
+	slog.WarnContext(ctx, "🚨 Refresh token reuse detected, revoking family", "user_id", t.UserID, "family_id", t.FamilyID)
+	if err := h.Tokens.RevokeRefreshFamily(ctx, t.FamilyID); err != nil {
+		return err
+	}
+	return errRefreshReused
+}
+
+// revokeRefreshFamily revokes every live token in the presented token's family.
+func (h *UserHandler) revokeRefreshFamily(ctx context.Context, raw string) {
+	t, err := h.Tokens.GetRefreshToken(ctx, hashToken(raw))
+	if errors.Is(err, repository.ErrNotFound) {
+		return
+	}
+	if err == nil {
+		err = h.Tokens.RevokeRefreshFamily(ctx, t.FamilyID)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "❌ Failed to revoke refresh token on logout", "err", err)
+	}
+}
+
+// hashToken returns the hex SHA-256 of a raw token; only hashes are persisted.
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomToken returns n random bytes encoded as unpadded base64url.
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
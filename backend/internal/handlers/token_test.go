@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brij-812/HyperLinkOS/internal/repository"
+)
+
+func newTestUserHandler(t *testing.T) *UserHandler {
+	t.Helper()
+	return NewUserHandler(nil, repository.NewMemoryRepo(), "secret", "hyperlinkos", 15, 24)
+}
+
+// sessionRequest calls handler with raw as the hl_refresh cookie.
+func sessionRequest(handler http.HandlerFunc, path, raw string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, nil)
+	req.AddCookie(&http.Cookie{Name: refreshCookieName, Value: raw})
+	w := httptest.NewRecorder()
+	handler(w, req)
+	return w
+}
+
+// refreshCookie returns the hl_refresh value set by a response, or "".
+func refreshCookie(w *httptest.ResponseRecorder) string {
+	for _, c := range w.Result().Cookies() {
+		if c.Name == refreshCookieName {
+			return c.Value
+		}
+	}
+	return ""
+}
+
+func TestRefreshRotatesWithinFamily(t *testing.T) {
+	h := newTestUserHandler(t)
+	ctx := context.Background()
+
+	first, err := h.issueRefreshToken(ctx, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := sessionRequest(h.Refresh, "/token/refresh", first)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	second := refreshCookie(w)
+	if second == "" || second == first {
+		t.Fatalf("expected a rotated refresh token, got %q", second)
+	}
+
+	old, _ := h.Tokens.GetRefreshToken(ctx, hashToken(first))
+	next, err := h.Tokens.GetRefreshToken(ctx, hashToken(second))
+	if err != nil {
+		t.Fatalf("expected the successor to be stored: %v", err)
+	}
+	if old.RevokedAt == nil || next.RevokedAt != nil {
+		t.Fatalf("expected the old token revoked and its successor live, got %+v / %+v", old, next)
+	}
+	if next.FamilyID != old.FamilyID || next.UserID != 1 {
+		t.Fatalf("expected the successor in the same family for the same user, got %+v", next)
+	}
+
+	// the successor keeps rotating
+	if w := sessionRequest(h.Refresh, "/token/refresh", second); w.Code != http.StatusOK {
+		t.Fatalf("expected the successor to refresh, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	h := newTestUserHandler(t)
+	ctx := context.Background()
+
+	stolen, _ := h.issueRefreshToken(ctx, 1, "")
+	other, _ := h.issueRefreshToken(ctx, 1, "") // another session of the same user
+	w := sessionRequest(h.Refresh, "/token/refresh", stolen)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	successor := refreshCookie(w)
+
+	// replaying the rotated token revokes everything issued from it
+	w = sessionRequest(h.Refresh, "/token/refresh", stolen)
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), errRefreshReused.Error()) {
+		t.Fatalf("expected 401 %q, got %d: %s", errRefreshReused, w.Code, w.Body)
+	}
+	if refreshCookie(w) != "" {
+		t.Fatal("expected the session cookies to be cleared")
+	}
+	if next, _ := h.Tokens.GetRefreshToken(ctx, hashToken(successor)); next.RevokedAt == nil {
+		t.Fatal("expected the successor to be revoked with its family")
+	}
+	if w := sessionRequest(h.Refresh, "/token/refresh", successor); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the revoked successor to be rejected, got %d", w.Code)
+	}
+
+	if w := sessionRequest(h.Refresh, "/token/refresh", other); w.Code != http.StatusOK {
+		t.Fatalf("expected other sessions to be untouched, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestRefreshRejectsExpiredAndUnknownTokens(t *testing.T) {
+	h := newTestUserHandler(t)
+	ctx := context.Background()
+
+	raw, tok, err := h.newRefreshToken(1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok.ExpiresAt = time.Now().Add(-time.Minute)
+	if err := h.Tokens.CreateRefreshToken(ctx, tok); err != nil {
+		t.Fatal(err)
+	}
+
+	w := sessionRequest(h.Refresh, "/token/refresh", raw)
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), errRefreshExpired.Error()) {
+		t.Fatalf("expected 401 %q, got %d: %s", errRefreshExpired, w.Code, w.Body)
+	}
+	if stored, _ := h.Tokens.GetRefreshToken(ctx, tok.TokenHash); stored.RevokedAt != nil {
+		t.Fatal("expected an expired token to be rejected without rotating it")
+	}
+
+	w = sessionRequest(h.Refresh, "/token/refresh", "not-a-token")
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), errRefreshInvalid.Error()) {
+		t.Fatalf("expected 401 %q, got %d: %s", errRefreshInvalid, w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	h.Refresh(w, httptest.NewRequest(http.MethodPost, "/token/refresh", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token, got %d", w.Code)
+	}
+}
+
+func TestLogoutRevokesFamily(t *testing.T) {
+	h := newTestUserHandler(t)
+	ctx := context.Background()
+
+	first, _ := h.issueRefreshToken(ctx, 1, "")
+	current := refreshCookie(sessionRequest(h.Refresh, "/token/refresh", first))
+
+	w := sessionRequest(h.Logout, "/logout", current)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	for _, c := range w.Result().Cookies() {
+		if c.MaxAge >= 0 {
+			t.Fatalf("expected cookie %s to be cleared, got %+v", c.Name, c)
+		}
+	}
+
+	for _, raw := range []string{first, current} {
+		if tok, _ := h.Tokens.GetRefreshToken(ctx, hashToken(raw)); tok.RevokedAt == nil {
+			t.Fatal("expected every token in the family to be revoked")
+		}
+	}
+	if w := sessionRequest(h.Refresh, "/token/refresh", current); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the logged-out session not to refresh, got %d", w.Code)
+	}
+
+	// logging out with an unknown token still clears the cookies
+	if w := sessionRequest(h.Logout, "/logout", "not-a-token"); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	unlockCookieName = "hl_unlock"
+
+	defaultUnlockTTL = 30 * time.Minute
+
+	// bcrypt only looks at the first 72 bytes of a password.
+	maxLinkPasswordBytes = 72
+)
+
+var unlockPage = template.Must(template.New("unlock").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Password required</title>
+</head>
+<body style="font-family: sans-serif; max-width: 24rem; margin: 4rem auto;">
+<h1>🔒 Password required</h1>
+<p>This link is protected. Enter its password to continue.</p>
+{{if .Error}}<p style="color: #b00020;">{{.Error}}</p>{{end}}
+<form method="post" action="/{{.Code}}">
+<input type="password" name="password" autofocus required autocomplete="current-password">
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+// 🔹 POST /{shortCode} — unlock a password-protected link (Public)
+// Expects a form field "password"; on success remembers the unlock in a
+// cookie scoped to the code and redirects to the destination.
+func (h *URLHandler) UnlockURL(w http.ResponseWriter, r *http.Request) {
+	shortCode := chi.URLParam(r, "shortCode")
+	if shortCode == "" {
+		http.Error(w, "short code missing", http.StatusBadRequest)
+		return
+	}
+
+	domainID, err := h.domainForHost(r.Context(), r.Host)
+	if err != nil {
+		writeRepoError(w, r, err, "short URL not found")
+		return
+	}
+	longURL, hash, err := h.Repo.GetProtectedURL(r.Context(), shortCode, domainID)
+	if err != nil {
+		h.writeRedirectError(w, r, err)
+		return
+	}
+
+	password := r.PostFormValue("password")
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		renderUnlockPage(w, r, shortCode, "Incorrect password.")
+		return
+	}
+
+	if err := h.Repo.ConsumeClick(r.Context(), shortCode); err != nil {
+		writeRepoError(w, r, err, "short URL not found")
+		return
+	}
+	h.setUnlockCookie(w, r, shortCode, hash)
+	h.recordClick(r, shortCode)
+	http.Redirect(w, r, longURL, http.StatusSeeOther)
+}
+
+// serveProtected redirects visitors holding a valid unlock cookie and asks
+// everyone else for the password. Protected destinations are always read
+// from storage, never from the redirect cache.
+func (h *URLHandler) serveProtected(w http.ResponseWriter, r *http.Request, code string, domainID int) {
+	longURL, hash, err := h.Repo.GetProtectedURL(r.Context(), code, domainID)
+	if err != nil {
+		h.writeRedirectError(w, r, err)
+		return
+	}
+
+	if !h.validUnlockCookie(r, code, hash) {
+		renderUnlockPage(w, r, code, "")
+		return
+	}
+
+	if err := h.Repo.ConsumeClick(r.Context(), code); err != nil {
+		writeRepoError(w, r, err, "short URL not found")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	h.recordClick(r, code)
+	http.Redirect(w, r, longURL, http.StatusFound)
+}
+
+func renderUnlockPage(w http.ResponseWriter, r *http.Request, code, errMsg string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.WriteHeader(http.StatusUnauthorized)
+	if err := unlockPage.Execute(w, struct{ Code, Error string }{code, errMsg}); err != nil {
+		slog.ErrorContext(r.Context(), "❌ Failed to render unlock page", "err", err)
+	}
+}
+
+func (h *URLHandler) unlockTTL() time.Duration {
+	if h.UnlockTTL <= 0 {
+		return defaultUnlockTTL
+	}
+	return h.UnlockTTL
+}
+
+// UnlockKey derives the unlock cookie key from the JWT secret, so the two
+// kinds of token never share a signing key.
+func UnlockKey(jwtSecret string) []byte {
+	if jwtSecret == "" {
+		return nil
+	}
+	mac := hmac.New(sha256.New, []byte(jwtSecret))
+	mac.Write([]byte("hyperlinkos unlock cookie"))
+	return mac.Sum(nil)
+}
+
+// unlockSignature binds a cookie to the code, its expiry and the current
+// password hash, so changing the password locks everyone out again.
+func (h *URLHandler) unlockSignature(code, expires, hash string) []byte {
+	mac := hmac.New(sha256.New, h.UnlockSecret)
+	mac.Write([]byte(code + "\n" + expires + "\n" + hash))
+	return mac.Sum(nil)
+}
+
+// setUnlockCookie remembers an unlock as "<expiry unix>.<signature>",
+// sent back only on requests for this code. Over TLS, or behind an https
+// public base URL, the cookie is never sent over plain HTTP.
+func (h *URLHandler) setUnlockCookie(w http.ResponseWriter, r *http.Request, code, hash string) {
+	if len(h.UnlockSecret) == 0 {
+		return
+	}
+	ttl := h.unlockTTL()
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	http.SetCookie(w, &http.Cookie{
+		Name:     unlockCookieName,
+		Value:    expires + "." + base64.RawURLEncoding.EncodeToString(h.unlockSignature(code, expires, hash)),
+		Path:     "/" + code,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || strings.HasPrefix(strings.ToLower(h.BaseURL), "https://"),
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   int(ttl / time.Second),
+	})
+}
+
+func (h *URLHandler) validUnlockCookie(r *http.Request, code, hash string) bool {
+	if len(h.UnlockSecret) == 0 {
+		return false
+	}
+	cookie, err := r.Cookie(unlockCookieName)
+	if err != nil {
+		return false
+	}
+	expires, sig, ok := strings.Cut(cookie.Value, ".")
+	if !ok {
+		return false
+	}
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return false
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(got, h.unlockSignature(code, expires, hash))
+}
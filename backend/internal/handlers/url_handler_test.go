@@ -2,27 +2,38 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/brij-812/HyperLinkOS/internal/models"
 	"github.com/brij-812/HyperLinkOS/internal/repository"
+	"github.com/go-chi/chi/v5"
 )
 
+// withUser mimics JWTAuth by injecting user_id into the request context.
+func withUser(req *http.Request, userID int) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), "user_id", userID))
+}
+
 func TestShortenAndMetrics(t *testing.T) {
 	repo := repository.NewMemoryRepo()
-	h := NewURLHandler(repo)
+	h := NewURLHandler(repo, nil, 0)
 
 	body := []byte(`{"url":"https://a.com"}`)
-	req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBuffer(body))
+	req := withUser(httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBuffer(body)), 1)
 	w := httptest.NewRecorder()
 	h.ShortenURL(w, req)
 	if w.Code != http.StatusOK {
 		t.Fatalf("unexpected status %d", w.Code)
 	}
 
-	req2 := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req2 := withUser(httptest.NewRequest(http.MethodGet, "/metrics", nil), 1)
 	w2 := httptest.NewRecorder()
 	h.GetMetrics(w2, req2)
 	if w2.Code != http.StatusOK {
@@ -35,3 +46,504 @@ func TestShortenAndMetrics(t *testing.T) {
 		t.Fatalf("expected a.com count 1, got %v", data)
 	}
 }
+
+func TestShortenWithAlias(t *testing.T) {
+	repo := repository.NewMemoryRepo()
+	h := NewURLHandler(repo, nil, 0)
+
+	shorten := func(body string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(body)), 1)
+		w := httptest.NewRecorder()
+		h.ShortenURL(w, req)
+		return w
+	}
+
+	w := shorten(`{"url":"https://a.com/launch","alias":"q4-launch"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for new alias, got %d", w.Code)
+	}
+	if u, err := repo.GetURL(context.Background(), "q4-launch", 0); err != nil || u != "https://a.com/launch" {
+		t.Fatalf("expected alias to resolve to https://a.com/launch, got %q", u)
+	}
+
+	if w := shorten(`{"url":"https://b.com","alias":"q4-launch"}`); w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for taken alias, got %d", w.Code)
+	}
+	if w := shorten(`{"url":"https://b.com","alias":"no"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for short alias, got %d", w.Code)
+	}
+	if w := shorten(`{"url":"https://b.com","alias":"has space"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid characters, got %d", w.Code)
+	}
+	if w := shorten(`{"url":"https://b.com","alias":"Metrics"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for reserved alias, got %d", w.Code)
+	}
+}
+
+// fixedCodes replays a list of codes, one per attempt.
+type fixedCodes []string
+
+func (f fixedCodes) Generate(_ string, attempt int) (string, error) {
+	return f[attempt%len(f)], nil
+}
+
+func TestShortenRetriesOnCollision(t *testing.T) {
+	repo := repository.NewMemoryRepo()
+	repo.Save(context.Background(), &models.Link{LongURL: "https://taken.com", Code: "taken1", UserID: 2})
+	h := NewURLHandler(repo, fixedCodes{"taken1", "fresh1"}, 3)
+
+	req := withUser(httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(`{"url":"https://b.com"}`)), 1)
+	w := httptest.NewRecorder()
+	h.ShortenURL(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d", w.Code)
+	}
+	if u, err := repo.GetURL(context.Background(), "fresh1", 0); err != nil || u != "https://b.com" {
+		t.Fatalf("expected retry to store https://b.com under fresh1, got %q", u)
+	}
+	if u, _ := repo.GetURL(context.Background(), "taken1", 0); u != "https://taken.com" {
+		t.Fatalf("colliding code was overwritten: %q", u)
+	}
+
+	// a generator that only ever collides must give up instead of looping
+	h = NewURLHandler(repo, fixedCodes{"taken1"}, 2)
+	req = withUser(httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(`{"url":"https://c.com"}`)), 1)
+	w = httptest.NewRecorder()
+	h.ShortenURL(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 after exhausting retries, got %d", w.Code)
+	}
+}
+
+func TestShortenSameURLForDifferentUsers(t *testing.T) {
+	repo := repository.NewMemoryRepo()
+	h := NewURLHandler(repo, nil, 0)
+
+	shorten := func(userID int, body string) models.ShortenResponse {
+		req := withUser(httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(body)), userID)
+		w := httptest.NewRecorder()
+		h.ShortenURL(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("user %d: unexpected status %d", userID, w.Code)
+		}
+		var resp models.ShortenResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp
+	}
+
+	a := shorten(1, `{"url":"https://a.com","shared":true}`)
+	b := shorten(2, `{"url":"https://a.com"}`)
+	if a.ShortURL == b.ShortURL {
+		t.Fatalf("expected distinct codes per user, both got %s", a.ShortURL)
+	}
+	if again := shorten(2, `{"url":"https://a.com"}`); again.ShortURL != b.ShortURL {
+		t.Fatalf("expected user 2 to get their existing code %s, got %s", b.ShortURL, again.ShortURL)
+	}
+	if urls, _ := repo.GetAllURLsByUser(context.Background(), 2); len(urls) != 1 {
+		t.Fatal("expected user 2 to see their own link")
+	}
+
+	// opting in to reuse returns user 1's shared link without creating a new one
+	if c := shorten(3, `{"url":"https://a.com","reuse_shared":true}`); c.ShortURL != a.ShortURL {
+		t.Fatalf("expected shared code %s, got %s", a.ShortURL, c.ShortURL)
+	}
+	if urls, _ := repo.GetAllURLsByUser(context.Background(), 3); len(urls) != 0 {
+		t.Fatal("reusing a shared link must not transfer ownership")
+	}
+}
+
+// syncRecorder writes clicks straight to the repo so tests don't need the background writer.
+type syncRecorder struct{ repo repository.Repository }
+
+func (s syncRecorder) Record(_ context.Context, ev models.ClickEvent) {
+	s.repo.SaveClicks(context.Background(), []models.ClickEvent{ev})
+}
+
+func TestRedirectRecordsClicksAndStats(t *testing.T) {
+	repo := repository.NewMemoryRepo()
+	h := NewURLHandler(repo, nil, 0)
+	h.Clicks = syncRecorder{repo}
+	repo.Save(context.Background(), &models.Link{LongURL: "https://a.com", Code: "abcd", UserID: 1})
+
+	router := chi.NewRouter()
+	router.Get("/{shortCode}", h.RedirectURL)
+	router.Get("/url/{code}/stats", func(w http.ResponseWriter, r *http.Request) {
+		h.GetLinkStats(w, withUser(r, 1))
+	})
+
+	for _, ip := range []string{"10.0.0.1:1", "10.0.0.1:2", "10.0.0.2:1"} {
+		req := httptest.NewRequest(http.MethodGet, "/abcd", nil)
+		req.RemoteAddr = ip
+		req.Header.Set("Referer", "https://news.example")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusFound {
+			t.Fatalf("expected 302, got %d", w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/url/abcd/stats?bucket=hour&days=1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected stats status %d", w.Code)
+	}
+	var stats models.LinkStats
+	json.Unmarshal(w.Body.Bytes(), &stats)
+	if stats.TotalClicks != 3 || stats.UniqueVisitors != 2 {
+		t.Fatalf("expected 3 clicks from 2 visitors, got %+v", stats)
+	}
+	if len(stats.Series) == 0 || stats.Series[len(stats.Series)-1].Clicks != 3 {
+		t.Fatalf("expected current bucket to hold 3 clicks, got %+v", stats.Series)
+	}
+
+	// stats are only visible to the owner
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("code", "abcd")
+	req := httptest.NewRequest(http.MethodGet, "/url/abcd/stats", nil)
+	req = withUser(req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx)), 2)
+	other := httptest.NewRecorder()
+	h.GetLinkStats(other, req)
+	if other.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for non-owner, got %d", other.Code)
+	}
+}
+
+func TestCustomDomainLinks(t *testing.T) {
+	repo := repository.NewMemoryRepo()
+	h := NewURLHandler(repo, nil, 0)
+	h.BaseURL = "https://hl.example.com"
+	h.Domains = repo
+
+	dh := NewDomainHandler(repo)
+	dh.LookupTXT = func(_ context.Context, name string) ([]string, error) {
+		d, _ := repo.GetDomain(context.Background(), 1, 1)
+		if name != "_hyperlinkos.go.example.com" {
+			return nil, nil
+		}
+		return []string{"v=spf1 -all", "hyperlinkos-verify=" + d.VerificationToken}, nil
+	}
+
+	router := chi.NewRouter()
+	router.Get("/{shortCode}", h.RedirectURL)
+	router.Post("/domains/{id}/verify", func(w http.ResponseWriter, r *http.Request) {
+		dh.Verify(w, withUser(r, 1))
+	})
+
+	w := httptest.NewRecorder()
+	dh.Create(w, withUser(httptest.NewRequest(http.MethodPost, "/domains", bytes.NewBufferString(`{"hostname":"Go.Example.com"}`)), 1))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 for new domain, got %d", w.Code)
+	}
+
+	shorten := func(body string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(body)), 1)
+		w := httptest.NewRecorder()
+		h.ShortenURL(w, req)
+		return w
+	}
+
+	// unverified domains can't be used yet
+	if w := shorten(`{"url":"https://a.com","domain":"go.example.com"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unverified domain, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/domains/1/verify", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected domain to verify, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var custom, def models.ShortenResponse
+	json.Unmarshal(shorten(`{"url":"https://a.com","domain":"go.example.com"}`).Body.Bytes(), &custom)
+	json.Unmarshal(shorten(`{"url":"https://b.com"}`).Body.Bytes(), &def)
+	customCode := custom.ShortURL[len("https://go.example.com/"):]
+	defCode := def.ShortURL[len("https://hl.example.com/"):]
+	if custom.ShortURL != "https://go.example.com/"+customCode || def.ShortURL != "https://hl.example.com/"+defCode {
+		t.Fatalf("unexpected short urls %q and %q", custom.ShortURL, def.ShortURL)
+	}
+
+	redirect := func(host, code string) int {
+		req := httptest.NewRequest(http.MethodGet, "/"+code, nil)
+		req.Host = host
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// codes only resolve on the domain they were issued for
+	if c := redirect("go.example.com:443", customCode); c != http.StatusFound {
+		t.Fatalf("expected 302 on custom domain, got %d", c)
+	}
+	if c := redirect("hl.example.com", customCode); c != http.StatusNotFound {
+		t.Fatalf("expected custom-domain code to miss on base host, got %d", c)
+	}
+	if c := redirect("go.example.com", defCode); c != http.StatusNotFound {
+		t.Fatalf("expected default code to miss on custom domain, got %d", c)
+	}
+	if c := redirect("hl.example.com", defCode); c != http.StatusFound {
+		t.Fatalf("expected 302 on base host, got %d", c)
+	}
+}
+
+func TestUpdateURL(t *testing.T) {
+	repo := repository.NewMemoryRepo()
+	h := NewURLHandler(repo, nil, 0)
+	repo.Save(context.Background(), &models.Link{LongURL: "https://typo.com/page", Code: "fixme", UserID: 1})
+
+	router := chi.NewRouter()
+	patch := func(userID int, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, withUser(httptest.NewRequest(http.MethodPatch, "/url/fixme", bytes.NewBufferString(body)), userID))
+		return w
+	}
+	router.Patch("/url/{code}", h.UpdateURL)
+
+	if w := patch(2, `{"long_url":"https://evil.com"}`); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for non-owner, got %d", w.Code)
+	}
+	if w := patch(1, `{}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for empty patch, got %d", w.Code)
+	}
+
+	w := patch(1, `{"long_url":"https://fixed.com/page","expiry_days":7}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", w.Code, w.Body.String())
+	}
+	var link models.Link
+	json.Unmarshal(w.Body.Bytes(), &link)
+	if link.LongURL != "https://fixed.com/page" || link.ExpiresAt == nil {
+		t.Fatalf("expected new destination with expiry, got %+v", link)
+	}
+	if u, _ := repo.GetURL(context.Background(), "fixme", 0); u != "https://fixed.com/page" {
+		t.Fatalf("expected redirect to follow the update, got %q", u)
+	}
+
+	domains, _ := repo.GetTopDomains(context.Background(), 1, 3)
+	if domains["typo.com"] != 0 || domains["fixed.com"] != 1 {
+		t.Fatalf("expected domain count to move to fixed.com, got %v", domains)
+	}
+
+	// expiry_days 0 removes the expiry again
+	var cleared models.Link
+	json.Unmarshal(patch(1, `{"expiry_days":0}`).Body.Bytes(), &cleared)
+	if cleared.ExpiresAt != nil {
+		t.Fatalf("expected expiry to be cleared, got %v", cleared.ExpiresAt)
+	}
+
+	// a scheduled link can't be made to expire before it goes live
+	activates := time.Now().Add(48 * time.Hour)
+	repo.Save(context.Background(), &models.Link{LongURL: "https://later.com", Code: "later", UserID: 1, ActivatesAt: &activates})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, withUser(httptest.NewRequest(http.MethodPatch, "/url/later", bytes.NewBufferString(`{"expiry_days":1}`)), 1))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an expiry before activation, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// downRepo simulates a storage outage on the redirect path.
+type downRepo struct{ *repository.MemoryRepo }
+
+func (downRepo) GetURL(context.Context, string, int) (string, error) {
+	return "", fmt.Errorf("GetURL: %w: connection refused", repository.ErrUnavailable)
+}
+
+func TestRedirectStatusMapping(t *testing.T) {
+	repo := repository.NewMemoryRepo()
+	past := time.Now().Add(-time.Hour)
+	repo.Save(context.Background(), &models.Link{LongURL: "https://old.com", Code: "gone1", UserID: 1, ExpiresAt: &past})
+
+	redirect := func(h *URLHandler, code string) int {
+		router := chi.NewRouter()
+		router.Get("/{shortCode}", h.RedirectURL)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+code, nil))
+		return w.Code
+	}
+
+	h := NewURLHandler(repo, nil, 0)
+	if c := redirect(h, "gone1"); c != http.StatusGone {
+		t.Fatalf("expected 410 for expired link, got %d", c)
+	}
+	if c := redirect(h, "nope1"); c != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown code, got %d", c)
+	}
+
+	// an outage must not look like a dead link
+	if c := redirect(NewURLHandler(downRepo{repo}, nil, 0), "gone1"); c != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 when storage is down, got %d", c)
+	}
+}
+
+func TestPasswordProtectedLink(t *testing.T) {
+	repo := repository.NewMemoryRepo()
+	h := NewURLHandler(repo, fixedCodes{"secret1", "plain1"}, 0)
+	h.UnlockSecret = []byte("test-secret")
+
+	shorten := func(body string) models.ShortenResponse {
+		req := withUser(httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(body)), 1)
+		w := httptest.NewRecorder()
+		h.ShortenURL(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("unexpected shorten status %d", w.Code)
+		}
+		var resp models.ShortenResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp
+	}
+	protected := shorten(`{"url":"https://docs.internal/plan","password":"hunter2"}`)
+	// the same URL without a password must not reuse the protected link
+	if plain := shorten(`{"url":"https://docs.internal/plan"}`); plain.ShortURL == protected.ShortURL {
+		t.Fatal("expected a separate unprotected link")
+	}
+
+	router := chi.NewRouter()
+	router.Get("/{shortCode}", h.RedirectURL)
+	router.Post("/{shortCode}", h.UnlockURL)
+	do := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	unlock := func(password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/secret1", bytes.NewBufferString("password="+password))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return do(req)
+	}
+
+	w := do(httptest.NewRequest(http.MethodGet, "/secret1", nil))
+	if w.Code != http.StatusUnauthorized || w.Header().Get("Location") != "" {
+		t.Fatalf("expected the unlock form, got %d to %q", w.Code, w.Header().Get("Location"))
+	}
+	if w := unlock("wrong"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a wrong password, got %d", w.Code)
+	}
+
+	w = unlock("hunter2")
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "https://docs.internal/plan" {
+		t.Fatalf("expected redirect after unlock, got %d to %q", w.Code, w.Header().Get("Location"))
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Path != "/secret1" {
+		t.Fatalf("expected one unlock cookie scoped to /secret1, got %+v", cookies)
+	}
+	if cookies[0].Secure {
+		t.Fatal("expected no Secure flag on a plain HTTP deployment")
+	}
+
+	// the cookie skips the prompt on repeat visits
+	req := httptest.NewRequest(http.MethodGet, "/secret1", nil)
+	req.AddCookie(cookies[0])
+	if w := do(req); w.Code != http.StatusFound {
+		t.Fatalf("expected 302 with unlock cookie, got %d", w.Code)
+	}
+
+	// a cookie signed for another code or with another secret is ignored
+	repo.Save(context.Background(), &models.Link{LongURL: "https://b.com", Code: "other1", UserID: 1, PasswordHash: "x"})
+	req = httptest.NewRequest(http.MethodGet, "/other1", nil)
+	req.AddCookie(cookies[0])
+	if w := do(req); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected cookie to be rejected for another code, got %d", w.Code)
+	}
+	h.UnlockSecret = []byte("rotated")
+	req = httptest.NewRequest(http.MethodGet, "/secret1", nil)
+	req.AddCookie(cookies[0])
+	if w := do(req); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected cookie to be rejected after secret rotation, got %d", w.Code)
+	}
+
+	// behind an https public origin the cookie is Secure even when TLS ends at a proxy
+	h.BaseURL = "https://hl.example.com"
+	if c := unlock("hunter2").Result().Cookies(); len(c) != 1 || !c[0].Secure {
+		t.Fatalf("expected a Secure unlock cookie, got %+v", c)
+	}
+
+	// unprotected links can't be "unlocked"
+	req = httptest.NewRequest(http.MethodPost, "/plain1", nil)
+	if w := do(req); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 when unlocking an unprotected link, got %d", w.Code)
+	}
+}
+
+func TestOneTimeLink(t *testing.T) {
+	repo := repository.NewMemoryRepo()
+	h := NewURLHandler(repo, fixedCodes{"once1"}, 0)
+
+	req := withUser(httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(`{"url":"https://dl.com/file","max_clicks":1}`)), 1)
+	w := httptest.NewRecorder()
+	h.ShortenURL(w, req)
+	var resp models.ShortenResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || resp.MaxClicks != 1 {
+		t.Fatalf("unexpected shorten response %d %+v", w.Code, resp)
+	}
+
+	router := chi.NewRouter()
+	router.Get("/{shortCode}", h.RedirectURL)
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/once1", nil))
+		return w
+	}
+	if w := get(); w.Code != http.StatusFound {
+		t.Fatalf("expected first visit to redirect, got %d", w.Code)
+	}
+	w = get()
+	if w.Code != http.StatusGone || !strings.Contains(w.Body.String(), "click limit") {
+		t.Fatalf("expected 410 click limit, got %d %q", w.Code, w.Body.String())
+	}
+
+	req = withUser(httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(`{"url":"https://dl.com/file","max_clicks":-1}`)), 1)
+	w = httptest.NewRecorder()
+	h.ShortenURL(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for negative max_clicks, got %d", w.Code)
+	}
+}
+
+func TestScheduledLink(t *testing.T) {
+	repo := repository.NewMemoryRepo()
+	h := NewURLHandler(repo, fixedCodes{"launch1"}, 0)
+
+	launch := time.Now().Add(time.Hour)
+	body, _ := json.Marshal(models.ShortenRequest{URL: "https://campaign.com", ActivatesAt: &launch})
+	req := withUser(httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBuffer(body)), 1)
+	w := httptest.NewRecorder()
+	h.ShortenURL(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected shorten status %d", w.Code)
+	}
+
+	router := chi.NewRouter()
+	router.Get("/{shortCode}", h.RedirectURL)
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/launch1", nil))
+		return w
+	}
+
+	if w := get(); w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "not live yet") {
+		t.Fatalf("expected default pending response, got %d %q", w.Code, w.Body.String())
+	}
+	h.PendingFallbackURL = "https://campaign.com/coming-soon"
+	if w := get(); w.Code != http.StatusFound || w.Header().Get("Location") != h.PendingFallbackURL {
+		t.Fatalf("expected fallback redirect, got %d to %q", w.Code, w.Header().Get("Location"))
+	}
+
+	// once live, the link redirects normally
+	past := time.Now().Add(-time.Minute)
+	repo.Save(context.Background(), &models.Link{LongURL: "https://campaign.com", Code: "live1", UserID: 1, ActivatesAt: &past})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/live1", nil))
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "https://campaign.com" {
+		t.Fatalf("expected live link to redirect, got %d", w.Code)
+	}
+
+	// the launch must come before the expiry
+	late := time.Now().Add(48 * time.Hour)
+	body, _ = json.Marshal(models.ShortenRequest{URL: "https://campaign.com", ActivatesAt: &late, ExpiryDays: 1})
+	w = httptest.NewRecorder()
+	h.ShortenURL(w, withUser(httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBuffer(body)), 1))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for activation after expiry, got %d", w.Code)
+	}
+}
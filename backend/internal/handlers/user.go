@@ -7,23 +7,28 @@ import (
 	"time"
 
 	"github.com/brij-812/HyperLinkOS/internal/models"
+	"github.com/brij-812/HyperLinkOS/internal/repository"
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type UserHandler struct {
-	DB                   *sql.DB
-	JWTSecret            []byte
-	JWTIssuer            string
-	AccessTokenExpiryMin int
+	DB                      *sql.DB
+	Tokens                  repository.RefreshTokenStore
+	JWTSecret               []byte
+	JWTIssuer               string
+	AccessTokenExpiryMin    int
+	RefreshTokenExpiryHours int
 }
 
-func NewUserHandler(db *sql.DB, secret, issuer string, accessExpiry int) *UserHandler {
+func NewUserHandler(db *sql.DB, tokens repository.RefreshTokenStore, secret, issuer string, accessExpiry, refreshExpiry int) *UserHandler {
 	return &UserHandler{
-		DB:                   db,
-		JWTSecret:            []byte(secret),
-		JWTIssuer:            issuer,
-		AccessTokenExpiryMin: accessExpiry,
+		DB:                      db,
+		Tokens:                  tokens,
+		JWTSecret:               []byte(secret),
+		JWTIssuer:               issuer,
+		AccessTokenExpiryMin:    accessExpiry,
+		RefreshTokenExpiryHours: refreshExpiry,
 	}
 }
 
@@ -60,9 +65,9 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var storedHash string
+	var storedHash, plan string
 	var userID int
-	err := h.DB.QueryRow(`SELECT id, password_hash FROM users WHERE email = $1`, req.Email).Scan(&userID, &storedHash)
+	err := h.DB.QueryRow(`SELECT id, password_hash, plan FROM users WHERE email = $1`, req.Email).Scan(&userID, &storedHash, &plan)
 	if err == sql.ErrNoRows {
 		http.Error(w, "invalid credentials", http.StatusUnauthorized)
 		return
@@ -77,33 +82,20 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// ✅ Create JWT
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		"iss":     h.JWTIssuer,
-		"exp":     time.Now().Add(time.Duration(h.AccessTokenExpiryMin) * time.Minute).Unix(),
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(h.JWTSecret)
+	tokenString, err := h.signAccessToken(userID, plan)
 	if err != nil {
 		http.Error(w, "failed to create token", http.StatusInternalServerError)
 		return
 	}
 
-	// ✅ Set cookies (secure + HttpOnly)
-	http.SetCookie(w, &http.Cookie{
-		Name:     "hl_jwt",
-		Value:    tokenString,
-		Path:     "/",
-		HttpOnly: true,
-		SameSite: http.SameSiteLaxMode,
-		MaxAge:   h.AccessTokenExpiryMin * 60,
-	})
-	http.SetCookie(w, &http.Cookie{
-		Name:   "hl_email",
-		Value:  req.Email,
-		Path:   "/",
-		MaxAge: h.AccessTokenExpiryMin * 60,
-	})
+	// ✅ Start a new refresh token family for this session
+	refreshToken, err := h.issueRefreshToken(r.Context(), userID, "")
+	if err != nil {
+		http.Error(w, "failed to create refresh token", http.StatusInternalServerError)
+		return
+	}
+
+	h.setSessionCookies(w, tokenString, refreshToken, req.Email)
 
 	json.NewEncoder(w).Encode(map[string]string{
 		"message": "login successful",
@@ -112,24 +104,74 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 // 🔹 POST /logout
 func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	// Revoke the refresh token family server-side so the session can't be resumed
+	if raw := refreshTokenFromRequest(r); raw != "" {
+		h.revokeRefreshFamily(r.Context(), raw)
+	}
+
 	// Clear cookies by overwriting them with expired ones
+	h.clearSessionCookies(w)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Logged out successfully",
+	})
+}
+
+// signAccessToken creates the short-lived JWT carried in the hl_jwt cookie.
+// plan selects the user's rate-limit tier.
+func (h *UserHandler) signAccessToken(userID int, plan string) (string, error) {
+	claims := jwt.MapClaims{
+		"user_id": userID,
+		"plan":    plan,
+		"iss":     h.JWTIssuer,
+		"exp":     time.Now().Add(time.Duration(h.AccessTokenExpiryMin) * time.Minute).Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(h.JWTSecret)
+}
+
+// ✅ Set cookies (secure + HttpOnly)
+func (h *UserHandler) setSessionCookies(w http.ResponseWriter, accessToken, refreshToken, email string) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     "hl_jwt",
-		Value:    "",
+		Value:    accessToken,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   h.AccessTokenExpiryMin * 60,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshCookieName,
+		Value:    refreshToken,
 		Path:     "/",
-		MaxAge:   -1,
 		HttpOnly: true,
 		SameSite: http.SameSiteLaxMode,
+		MaxAge:   h.refreshTTLSeconds(),
 	})
 	http.SetCookie(w, &http.Cookie{
 		Name:   "hl_email",
-		Value:  "",
+		Value:  email,
 		Path:   "/",
-		MaxAge: -1,
+		MaxAge: h.refreshTTLSeconds(),
 	})
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Logged out successfully",
+func (h *UserHandler) clearSessionCookies(w http.ResponseWriter) {
+	for _, name := range []string{"hl_jwt", refreshCookieName} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			Path:     "/",
+			MaxAge:   -1,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:   "hl_email",
+		Value:  "",
+		Path:   "/",
+		MaxAge: -1,
 	})
 }
@@ -0,0 +1,57 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNewHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h, err := NewHandler(&buf, "warn", "text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(h).With("component", "test")
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	logger.InfoContext(ctx, "dropped below level")
+	logger.WarnContext(ctx, "kept")
+	logger.Warn("no request")
+
+	out := buf.String()
+	if strings.Contains(out, "dropped") {
+		t.Errorf("expected info to be filtered at warn level, got %q", out)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", out)
+	}
+	if !strings.Contains(lines[0], "request_id=req-1") || !strings.Contains(lines[0], "component=test") {
+		t.Errorf("expected request ID and logger attrs, got %q", lines[0])
+	}
+	if strings.Contains(lines[1], "request_id") {
+		t.Errorf("expected no request ID without one in context, got %q", lines[1])
+	}
+
+	buf.Reset()
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0x4b, 0xf9},
+		SpanID:  trace.SpanID{0x01},
+	})
+	logger.WarnContext(trace.ContextWithSpanContext(ctx, sc), "traced")
+	if out := buf.String(); !strings.Contains(out, "trace_id="+sc.TraceID().String()) ||
+		!strings.Contains(out, "span_id="+sc.SpanID().String()) {
+		t.Errorf("expected trace and span IDs, got %q", out)
+	}
+
+	for _, bad := range [][2]string{{"loud", "json"}, {"info", "xml"}} {
+		if _, err := NewHandler(&buf, bad[0], bad[1]); err == nil {
+			t.Errorf("expected level %q format %q to be rejected", bad[0], bad[1])
+		}
+	}
+}
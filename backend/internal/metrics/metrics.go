@@ -0,0 +1,125 @@
+// Package metrics holds the Prometheus collectors exported on
+// /metrics/prometheus. Collectors are registered on Registry rather than the
+// global default so tests and embedders get a predictable set.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
+)
+
+const namespace = "hyperlinkos"
+
+// Registry holds every HyperLinkOS collector plus the Go runtime and process ones.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests counts finished requests by method, chi route pattern and status.
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPDuration observes request latency by method and chi route pattern.
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route pattern.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	// RedirectCache counts shorturl: cache lookups by result (hit or miss).
+	RedirectCache = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "redirect_cache_lookups_total",
+		Help:      "Lookups of the shorturl: redirect cache by result.",
+	}, []string{"result"})
+
+	// RateLimitRejections counts requests refused with 429 by route group and
+	// the kind of principal that was limited (plan, api_key, user, ip).
+	RateLimitRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_rejections_total",
+		Help:      "Requests rejected by rate limiting, by group and principal kind.",
+	}, []string{"group", "principal"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		RedirectCache,
+		RateLimitRejections,
+	)
+}
+
+// RedirectCacheLookup records one shorturl: cache lookup.
+func RedirectCacheLookup(hit bool) {
+	if hit {
+		RedirectCache.WithLabelValues("hit").Inc()
+		return
+	}
+	RedirectCache.WithLabelValues("miss").Inc()
+}
+
+// RegisterDB exports the connection pool stats of db (sql.DB.Stats) labelled db_name=name.
+func RegisterDB(db *sql.DB, name string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterRedis exports the connection pool stats of rdb.
+func RegisterRedis(rdb *redis.Client) {
+	Registry.MustRegister(&redisPoolCollector{rdb: rdb})
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+var (
+	redisHits = prometheus.NewDesc(namespace+"_redis_pool_hits_total",
+		"Times a free connection was found in the Redis pool.", nil, nil)
+	redisMisses = prometheus.NewDesc(namespace+"_redis_pool_misses_total",
+		"Times a free connection was not found in the Redis pool.", nil, nil)
+	redisTimeouts = prometheus.NewDesc(namespace+"_redis_pool_timeouts_total",
+		"Times waiting for a Redis pool connection timed out.", nil, nil)
+	redisTotalConns = prometheus.NewDesc(namespace+"_redis_pool_connections",
+		"Connections currently in the Redis pool.", nil, nil)
+	redisIdleConns = prometheus.NewDesc(namespace+"_redis_pool_idle_connections",
+		"Idle connections in the Redis pool.", nil, nil)
+	redisStaleConns = prometheus.NewDesc(namespace+"_redis_pool_stale_connections_total",
+		"Stale connections removed from the Redis pool.", nil, nil)
+)
+
+// redisPoolCollector reads go-redis pool stats at scrape time.
+type redisPoolCollector struct {
+	rdb *redis.Client
+}
+
+func (c *redisPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- redisHits
+	ch <- redisMisses
+	ch <- redisTimeouts
+	ch <- redisTotalConns
+	ch <- redisIdleConns
+	ch <- redisStaleConns
+}
+
+func (c *redisPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.rdb.PoolStats()
+	ch <- prometheus.MustNewConstMetric(redisHits, prometheus.CounterValue, float64(s.Hits))
+	ch <- prometheus.MustNewConstMetric(redisMisses, prometheus.CounterValue, float64(s.Misses))
+	ch <- prometheus.MustNewConstMetric(redisTimeouts, prometheus.CounterValue, float64(s.Timeouts))
+	ch <- prometheus.MustNewConstMetric(redisTotalConns, prometheus.GaugeValue, float64(s.TotalConns))
+	ch <- prometheus.MustNewConstMetric(redisIdleConns, prometheus.GaugeValue, float64(s.IdleConns))
+	ch <- prometheus.MustNewConstMetric(redisStaleConns, prometheus.CounterValue, float64(s.StaleConns))
+}
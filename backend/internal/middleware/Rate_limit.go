@@ -1,78 +1,161 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
 	"math"
-	"net"
 	"net/http"
-	"strings"
+	"strconv"
 	"time"
 
 	"github.com/brij-812/HyperLinkOS/internal/cache"
+	"github.com/brij-812/HyperLinkOS/internal/metrics"
+	"github.com/brij-812/HyperLinkOS/internal/ratelimit"
 )
 
+// Route groups RateLimit can be applied to; RegisterRoutes wires them.
 const (
-	windowSecs = 60
-	userLimit  = 10
-	ipLimit    = 30
+	RateGroupAuth     = "auth"     // signup, login, logout, token refresh
+	RateGroupAPI      = "api"      // every authenticated endpoint
+	RateGroupShorten  = "shorten"  // POST /shorten
+	RateGroupRedirect = "redirect" // public short-link redirects
+	RateGroupUnlock   = "unlock"   // password attempts on protected links
 )
 
-func RateLimit(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		now := time.Now().Unix()
-		window := now / windowSecs
-
-		userID := r.Context().Value("user_id")
-		var keyBase string
-		if userID != nil {
-			keyBase = fmt.Sprintf("rate:user:%v", userID)
-		} else {
-			keyBase = fmt.Sprintf("rate:ip:%s", clientIP(r))
-		}
+// RateLimitConfig configures RateLimit; see InitRateLimit.
+type RateLimitConfig struct {
+	// Limiter keeps the counters: ratelimit.Redis shares them between
+	// replicas, ratelimit.Local keeps them in this process.
+	Limiter ratelimit.Limiter
+	// Groups maps a route group to its limits per principal kind. Groups
+	// without an entry are not limited.
+	Groups map[string]ratelimit.Group
+	// OnOutage applies when Limiter fails: cache.FailOpen lets requests
+	// through, cache.FailClosed answers 503, and cache.FailLocal (default)
+	// keeps counting in process, so each replica enforces the limits on
+	// its own until the shared store is back.
+	OnOutage cache.Policy
+}
 
-		currKey := fmt.Sprintf("%s:%d", keyBase, window)
-		prevKey := fmt.Sprintf("%s:%d", keyBase, window-1)
+var (
+	rateLimiter       ratelimit.Limiter
+	rateLimitGroups   map[string]ratelimit.Group
+	rateLimitOutage                     = cache.FailLocal
+	rateLimitFallback ratelimit.Limiter = ratelimit.NewLocal(0)
+	clock                               = time.Now
+)
 
-		// increment current counter
-		pipe := cache.Client().TxPipeline()
-		currCount := pipe.Incr(r.Context(), currKey)
-		pipe.Expire(r.Context(), currKey, time.Duration(windowSecs*2)*time.Second)
-		_, _ = pipe.Exec(r.Context())
+// InitRateLimit enables RateLimit; until it is called nothing is limited.
+func InitRateLimit(cfg RateLimitConfig) {
+	rateLimiter = cfg.Limiter
+	rateLimitGroups = cfg.Groups
+	rateLimitOutage = cfg.OnOutage
+	if rateLimitOutage == "" {
+		rateLimitOutage = cache.FailLocal
+	}
+	rateLimitFallback = ratelimit.NewLocal(0)
+}
 
-		prevVal, _ := cache.Client().Get(r.Context(), prevKey).Int64()
-		currVal := currCount.Val()
+// DefaultRateLimits are the limits of groups that aren't configured.
+func DefaultRateLimits() map[string]ratelimit.Group {
+	return map[string]ratelimit.Group{
+		RateGroupShorten: {
+			ratelimit.PrincipalUser: {Algorithm: ratelimit.SlidingWindow, Limit: 10, Window: time.Minute},
+			ratelimit.PrincipalIP:   {Algorithm: ratelimit.SlidingWindow, Limit: 30, Window: time.Minute},
+		},
+		RateGroupRedirect: {
+			ratelimit.PrincipalIP: {Algorithm: ratelimit.SlidingWindow, Limit: 120, Window: time.Minute},
+		},
+		RateGroupUnlock: {
+			ratelimit.PrincipalIP: {Algorithm: ratelimit.SlidingWindow, Limit: 10, Window: time.Minute},
+		},
+	}
+}
 
-		elapsed := float64(now%windowSecs) / float64(windowSecs)
-		blended := float64(prevVal)*(1.0-elapsed) + float64(currVal)
+// RateLimit enforces the limits configured for group. The most specific
+// principal with a configured limit is counted: the user's plan tier, then
+// the API key, then the user, then the client IP.
+func RateLimit(group string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			g, ok := rateLimitGroups[group]
+			if !ok || rateLimiter == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			policy, kind, principal, ok := rateLimitPrincipal(r, g)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
 
-		limit := userLimit
-		if userID == nil {
-			limit = ipLimit
-		}
+			key := fmt.Sprintf("rate:%s:%s", group, principal)
+			now := clock()
+			d, err := rateLimiter.Allow(r.Context(), key, policy, now)
+			if err != nil {
+				if !errors.Is(err, cache.ErrUnavailable) { // the breaker already reported the outage
+					slog.WarnContext(r.Context(), "⚠️ Rate limiter error", "policy", rateLimitOutage, "err", err)
+				}
+				switch rateLimitOutage {
+				case cache.FailOpen:
+					next.ServeHTTP(w, r)
+					return
+				case cache.FailClosed:
+					w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(policy.Window)))
+					http.Error(w, "rate limiter unavailable", http.StatusServiceUnavailable)
+					return
+				default:
+					d, _ = rateLimitFallback.Allow(r.Context(), key, policy, now)
+				}
+			}
 
-		remaining := int64(math.Max(0, float64(limit)-blended))
-		resetIn := windowSecs - int(now%windowSecs)
+			// 🔹 Standard Rate-Limit headers (like GitHub)
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(d.Limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(d.Remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(d.Reset)))
 
-		// 🔹 Standard Rate-Limit headers (like GitHub)
-		w.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%d", limit))
-		w.Header().Set("X-RateLimit-Remaining", fmt.Sprintf("%d", remaining))
-		w.Header().Set("X-RateLimit-Reset", fmt.Sprintf("%d", resetIn))
+			if !d.Allowed {
+				retryAfter := max(1, ceilSeconds(d.RetryAfter))
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				metrics.RateLimitRejections.WithLabelValues(group, kind).Inc()
+				w.WriteHeader(http.StatusTooManyRequests)
+				w.Write([]byte(fmt.Sprintf("Rate limit exceeded. Try again in %d seconds.", retryAfter)))
+				return
+			}
 
-		if blended > float64(limit) {
-			w.WriteHeader(http.StatusTooManyRequests)
-			w.Write([]byte(fmt.Sprintf("Rate limit exceeded. Try again in %d seconds.", resetIn)))
-			return
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitPrincipal picks the policy, the principal kind it is configured
+// for and the counter identity for r.
+// Plan limits are counted per user, API key limits per key.
+func rateLimitPrincipal(r *http.Request, g ratelimit.Group) (ratelimit.Policy, string, string, bool) {
+	type candidate struct{ kind, id string }
+	var candidates []candidate
+
+	if userID, ok := r.Context().Value("user_id").(int); ok {
+		user := fmt.Sprintf("user:%d", userID)
+		if plan, _ := r.Context().Value("plan").(string); plan != "" {
+			candidates = append(candidates, candidate{ratelimit.PlanPrefix + plan, user})
+		}
+		if keyID, ok := r.Context().Value("api_key_id").(int); ok {
+			candidates = append(candidates, candidate{ratelimit.PrincipalAPIKey, fmt.Sprintf("key:%d", keyID)})
 		}
+		candidates = append(candidates, candidate{ratelimit.PrincipalUser, user})
+	}
+	candidates = append(candidates, candidate{ratelimit.PrincipalIP, "ip:" + ClientIP(r)})
 
-		next.ServeHTTP(w, r)
-	})
+	for _, c := range candidates {
+		if p, ok := g[c.kind]; ok {
+			return p, c.kind, c.id, true
+		}
+	}
+	return ratelimit.Policy{}, "", "", false
 }
 
-func clientIP(r *http.Request) string {
-	ip := r.Header.Get("X-Forwarded-For")
-	if ip != "" {
-		return strings.Split(ip, ",")[0]
-	}
-	host, _, _ := net.SplitHostPort(r.RemoteAddr)
-	return host
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
 }
@@ -1,19 +1,35 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/brij-812/HyperLinkOS/internal/cache"
+	"github.com/brij-812/HyperLinkOS/internal/ratelimit"
 )
 
+var ipLimit = DefaultRateLimits()[RateGroupShorten][ratelimit.PrincipalIP].Limit
+
+// useLocalRateLimit enables the default limits with in-process counters and
+// a controllable clock, restoring both when the test ends.
+func useLocalRateLimit(t *testing.T) *time.Time {
+	now := time.Unix(1_700_000_040, 0) // start of a window
+	InitRateLimit(RateLimitConfig{Limiter: ratelimit.NewLocal(0), Groups: DefaultRateLimits()})
+	clock = func() time.Time { return now }
+	t.Cleanup(func() {
+		InitRateLimit(RateLimitConfig{})
+		clock = time.Now
+	})
+	return &now
+}
+
 func TestRateLimitMiddleware(t *testing.T) {
-	// make sure Redis is running locally
-	cache.InitRedis("localhost:6379", "", 0)
+	useLocalRateLimit(t)
 
-	handler := RateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := RateLimit(RateGroupShorten)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("ok"))
 	}))
@@ -29,7 +45,7 @@ func TestRateLimitMiddleware(t *testing.T) {
 
 	// hit the same IP repeatedly up to the limit
 	ip := "127.0.0.1:10000"
-	for i := 1; i <= userLimit; i++ {
+	for i := 1; i <= ipLimit; i++ {
 		resp := doRequest(ip)
 		if resp.Code != http.StatusOK {
 			t.Fatalf("expected 200 OK for request %d, got %d", i, resp.Code)
@@ -43,6 +59,9 @@ func TestRateLimitMiddleware(t *testing.T) {
 	}
 
 	// verify headers exist
+	if got := resp.Header().Get("Retry-After"); got == "" || got == "0" {
+		t.Errorf("expected a positive Retry-After header, got %q", got)
+	}
 	if resp.Header().Get("X-RateLimit-Limit") == "" {
 		t.Errorf("missing X-RateLimit-Limit header")
 	}
@@ -57,16 +76,16 @@ func TestRateLimitMiddleware(t *testing.T) {
 }
 
 func TestRateLimitReset(t *testing.T) {
-	cache.InitRedis("localhost:6379", "", 0)
+	now := useLocalRateLimit(t)
 
-	handler := RateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := RateLimit(RateGroupShorten)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
 	ip := "127.0.0.1:20000"
 
 	// trigger the limit
-	for i := 0; i < userLimit; i++ {
+	for i := 0; i < ipLimit; i++ {
 		req := httptest.NewRequest("GET", "/shorten", nil)
 		req.RemoteAddr = ip
 		w := httptest.NewRecorder()
@@ -83,8 +102,7 @@ func TestRateLimitReset(t *testing.T) {
 	}
 
 	// wait for window to reset
-	t.Log("waiting for rate limit window to reset...")
-	time.Sleep(time.Duration(windowSecs+2) * time.Second)
+	*now = now.Add(2 * time.Minute)
 
 	req = httptest.NewRequest("GET", "/shorten", nil)
 	req.RemoteAddr = ip
@@ -94,3 +112,109 @@ func TestRateLimitReset(t *testing.T) {
 		t.Fatalf("expected 200 after window reset, got %d", w.Code)
 	}
 }
+
+// downLimiter behaves like Redis behind an open circuit breaker.
+type downLimiter struct{}
+
+func (downLimiter) Allow(context.Context, string, ratelimit.Policy, time.Time) (ratelimit.Decision, error) {
+	return ratelimit.Decision{}, cache.ErrUnavailable
+}
+
+func TestRateLimitOutagePolicies(t *testing.T) {
+	useLocalRateLimit(t)
+	handler := RateLimit(RateGroupShorten)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	hit := func() int {
+		req := httptest.NewRequest("GET", "/shorten", nil)
+		req.RemoteAddr = "127.0.0.1:30000"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+	initDown := func(p cache.Policy) {
+		InitRateLimit(RateLimitConfig{Limiter: downLimiter{}, Groups: DefaultRateLimits(), OnOutage: p})
+	}
+
+	initDown(cache.FailOpen)
+	for i := 0; i <= ipLimit; i++ {
+		if code := hit(); code != http.StatusOK {
+			t.Fatalf("fail-open: expected 200, got %d", code)
+		}
+	}
+
+	initDown(cache.FailClosed)
+	if code := hit(); code != http.StatusServiceUnavailable {
+		t.Fatalf("fail-closed: expected 503, got %d", code)
+	}
+
+	// the local fallback still enforces the limit during the outage
+	initDown(cache.FailLocal)
+	for i := 1; i <= ipLimit; i++ {
+		if code := hit(); code != http.StatusOK {
+			t.Fatalf("local fallback: expected 200 for request %d, got %d", i, code)
+		}
+	}
+	if code := hit(); code != http.StatusTooManyRequests {
+		t.Fatalf("local fallback: expected 429, got %d", code)
+	}
+}
+
+func TestRateLimitPrincipals(t *testing.T) {
+	useLocalRateLimit(t)
+	policy := func(limit int) ratelimit.Policy {
+		return ratelimit.Policy{Algorithm: ratelimit.TokenBucket, Limit: limit, Window: time.Minute}
+	}
+	InitRateLimit(RateLimitConfig{
+		Limiter: ratelimit.NewLocal(0),
+		Groups: map[string]ratelimit.Group{
+			RateGroupAPI: {
+				ratelimit.PrincipalIP:     policy(1),
+				ratelimit.PrincipalUser:   policy(2),
+				ratelimit.PrincipalAPIKey: policy(3),
+				"plan:pro":                policy(5),
+			},
+		},
+	})
+
+	handler := RateLimit(RateGroupAPI)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	unlimited := RateLimit(RateGroupRedirect)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	// allowed counts how many requests pass before the first 429
+	allowed := func(h http.Handler, values map[string]any) int {
+		for n := 0; n < 20; n++ {
+			req := httptest.NewRequest("GET", "/all", nil)
+			ctx := req.Context()
+			for k, v := range values {
+				ctx = context.WithValue(ctx, k, v)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req.WithContext(ctx))
+			if w.Code == http.StatusTooManyRequests {
+				return n
+			}
+		}
+		return 20
+	}
+
+	tests := []struct {
+		name   string
+		values map[string]any
+		want   int
+	}{
+		{"anonymous", nil, 1},
+		{"user", map[string]any{"user_id": 1}, 2},
+		{"api key", map[string]any{"user_id": 2, "api_key_id": 9}, 3},
+		{"plan beats api key", map[string]any{"user_id": 3, "api_key_id": 10, "plan": "pro"}, 5},
+		{"unconfigured plan", map[string]any{"user_id": 4, "plan": "free"}, 2},
+	}
+	for _, tt := range tests {
+		if got := allowed(handler, tt.values); got != tt.want {
+			t.Errorf("%s: %d requests allowed, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	if got := allowed(unlimited, nil); got != 20 {
+		t.Errorf("unconfigured group: expected no limit, got %d", got)
+	}
+}
@@ -2,11 +2,15 @@ package middleware
 
 import (
 	"context"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/brij-812/HyperLinkOS/internal/models"
+	"github.com/brij-812/HyperLinkOS/internal/repository"
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -17,9 +21,28 @@ func InitJWTSecret(secret string) {
 	jwtSecret = []byte(secret)
 }
 
+// APIKeyLookup resolves a raw API key to its owner and granted scopes.
+// Unknown, revoked and expired keys are repository.ErrNotFound.
+type APIKeyLookup interface {
+	LookupAPIKey(ctx context.Context, raw string) (models.APIKeyOwner, error)
+}
+
+var apiKeys APIKeyLookup
+
+// InitAPIKeys enables "Authorization: ApiKey <key>" authentication
+func InitAPIKeys(lookup APIKeyLookup) {
+	apiKeys = lookup
+}
+
 // JWTAuth validates the JWT (from cookie or Authorization header)
 func JWTAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 🔑 API keys take a separate path and carry explicit scopes
+		if rawKey, ok := strings.CutPrefix(r.Header.Get("Authorization"), "ApiKey "); ok {
+			apiKeyAuth(w, r, next, strings.TrimSpace(rawKey))
+			return
+		}
+
 		var tokenString string
 
 		// 1️⃣ Prefer cookie (browser clients)
@@ -75,10 +98,72 @@ func JWTAuth(next http.Handler) http.Handler {
 			return
 		}
 
-		log.Printf("✅ Authenticated request by user_id=%d", userID)
+		slog.DebugContext(r.Context(), "✅ Authenticated request", "user_id", userID)
 
 		// ✅ Inject into context
 		ctx := context.WithValue(r.Context(), "user_id", userID)
+		if plan, ok := claims["plan"].(string); ok {
+			ctx = context.WithValue(ctx, "plan", plan)
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+func apiKeyAuth(w http.ResponseWriter, r *http.Request, next http.Handler, rawKey string) {
+	if apiKeys == nil || rawKey == "" {
+		http.Error(w, "invalid api key", http.StatusUnauthorized)
+		return
+	}
+
+	owner, err := apiKeys.LookupAPIKey(r.Context(), rawKey)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, "invalid api key", http.StatusUnauthorized)
+		return
+	case err != nil:
+		// the key may well be valid; don't make the client discard it
+		http.Error(w, "service temporarily unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	userID, scopes := owner.UserID, owner.Scopes
+	if scopes == nil {
+		// nil means "session" (all scopes) downstream, so a key never gets it
+		scopes = []string{}
+	}
+
+	slog.DebugContext(r.Context(), "✅ Authenticated API key request", "user_id", userID, "api_key_id", owner.KeyID)
+
+	ctx := context.WithValue(r.Context(), "user_id", userID)
+	ctx = context.WithValue(ctx, "scopes", scopes)
+	ctx = context.WithValue(ctx, "api_key_id", owner.KeyID)
+	if owner.Plan != "" {
+		ctx = context.WithValue(ctx, "plan", owner.Plan)
+	}
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// RequireScope rejects API-key requests that weren't granted scope.
+// Cookie/JWT sessions carry no scope list and are always allowed.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scopes, isAPIKey := r.Context().Value("scopes").([]string)
+			if isAPIKey && !slices.Contains(scopes, scope) {
+				http.Error(w, "api key lacks scope "+scope, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SessionOnly rejects API-key requests, e.g. so a key can't mint more keys.
+func SessionOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, isAPIKey := r.Context().Value("scopes").([]string); isAPIKey {
+			http.Error(w, "not available to api keys", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brij-812/HyperLinkOS/internal/models"
+	"github.com/brij-812/HyperLinkOS/internal/repository"
+)
+
+type fakeKeys map[string][]string
+
+func (f fakeKeys) LookupAPIKey(_ context.Context, raw string) (models.APIKeyOwner, error) {
+	if raw == "hlk_outage" {
+		return models.APIKeyOwner{}, repository.ErrUnavailable
+	}
+	scopes, ok := f[raw]
+	if !ok {
+		return models.APIKeyOwner{}, repository.ErrNotFound
+	}
+	return models.APIKeyOwner{KeyID: 1, UserID: 7, Plan: "free", Scopes: scopes}, nil
+}
+
+func TestAPIKeyAuthAndScopes(t *testing.T) {
+	InitAPIKeys(fakeKeys{"hlk_reader": {"links:read"}})
+	defer InitAPIKeys(nil)
+
+	var gotUser int
+	handler := JWTAuth(RequireScope("links:read")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUser, _ = r.Context().Value("user_id").(int)
+	})))
+	writeHandler := JWTAuth(RequireScope("links:write")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	sessionHandler := JWTAuth(SessionOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	do := func(h http.Handler, auth string) int {
+		req := httptest.NewRequest(http.MethodGet, "/all", nil)
+		req.Header.Set("Authorization", auth)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := do(handler, "ApiKey hlk_reader"); code != http.StatusOK || gotUser != 7 {
+		t.Fatalf("expected 200 for user 7, got %d (user %d)", code, gotUser)
+	}
+	if code := do(handler, "ApiKey hlk_unknown"); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for unknown key, got %d", code)
+	}
+	if code := do(handler, "ApiKey hlk_outage"); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 when keys can't be checked, got %d", code)
+	}
+	if code := do(writeHandler, "ApiKey hlk_reader"); code != http.StatusForbidden {
+		t.Fatalf("expected 403 for missing scope, got %d", code)
+	}
+	if code := do(sessionHandler, "ApiKey hlk_reader"); code != http.StatusForbidden {
+		t.Fatalf("expected 403 for session-only route, got %d", code)
+	}
+}
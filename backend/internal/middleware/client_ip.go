@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Headers a trusted proxy can report the client address in.
+const (
+	HeaderXForwardedFor = "X-Forwarded-For"
+	HeaderForwarded     = "Forwarded" // RFC 7239
+	HeaderXRealIP       = "X-Real-IP"
+)
+
+var (
+	trustedProxies []netip.Prefix
+	clientIPHeader = HeaderXForwardedFor
+)
+
+// InitClientIP sets which peers may report the client address and in which
+// header. Forwarding headers from any other peer are ignored, so clients
+// can't pick their own IP. Entries are CIDRs or single addresses.
+func InitClientIP(header string, trusted []string) error {
+	switch http.CanonicalHeaderKey(header) {
+	case "", HeaderXForwardedFor:
+		header = HeaderXForwardedFor
+	case HeaderForwarded:
+		header = HeaderForwarded
+	case http.CanonicalHeaderKey(HeaderXRealIP):
+		header = HeaderXRealIP
+	default:
+		return fmt.Errorf("unsupported client IP header %q (want %s, %s or %s)", header, HeaderXForwardedFor, HeaderForwarded, HeaderXRealIP)
+	}
+
+	prefixes := make([]netip.Prefix, 0, len(trusted))
+	for _, entry := range trusted {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+			}
+			entry = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()).String()
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	clientIPHeader = header
+	trustedProxies = prefixes
+	return nil
+}
+
+// RealIP resolves the client address once and stores it in the request
+// context for rate limiting, abuse detection and click analytics.
+func RealIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), "client_ip", resolveClientIP(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ClientIP returns the caller's IP address as resolved by RealIP.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value("client_ip").(string); ok {
+		return ip
+	}
+	return resolveClientIP(r)
+}
+
+// resolveClientIP walks the forwarding chain from the nearest hop outwards
+// and returns the first address that isn't a trusted proxy. Entries left of
+// it were supplied by the client and are never used.
+func resolveClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return host
+	}
+	ip = ip.Unmap()
+	if !isTrustedProxy(ip) {
+		return ip.String()
+	}
+
+	hops := forwardedHops(r)
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, ok := parseHop(hops[i])
+		if !ok {
+			break // "unknown" or obfuscated: the last trusted hop is all we know
+		}
+		ip = hop
+		if !isTrustedProxy(ip) {
+			break
+		}
+	}
+	return ip.String()
+}
+
+func isTrustedProxy(ip netip.Addr) bool {
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedHops lists the addresses in the configured header, client first.
+func forwardedHops(r *http.Request) []string {
+	var hops []string
+	switch clientIPHeader {
+	case HeaderXRealIP:
+		if v := strings.TrimSpace(r.Header.Get(HeaderXRealIP)); v != "" {
+			hops = append(hops, v)
+		}
+	case HeaderForwarded:
+		for _, line := range r.Header.Values(HeaderForwarded) {
+			for _, element := range strings.Split(line, ",") {
+				hops = append(hops, forwardedFor(element))
+			}
+		}
+	default:
+		for _, line := range r.Header.Values(HeaderXForwardedFor) {
+			for _, entry := range strings.Split(line, ",") {
+				hops = append(hops, strings.TrimSpace(entry))
+			}
+		}
+	}
+	return hops
+}
+
+// forwardedFor extracts the for= parameter of one Forwarded element,
+// e.g. `for="[2001:db8::17]:4711";proto=https` → `[2001:db8::17]:4711`.
+func forwardedFor(element string) string {
+	for _, pair := range strings.Split(element, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && strings.EqualFold(key, "for") {
+			return strings.Trim(value, `"`)
+		}
+	}
+	return ""
+}
+
+// parseHop parses an address with an optional port; IPv6 may be bracketed.
+func parseHop(s string) (netip.Addr, bool) {
+	if addrPort, err := netip.ParseAddrPort(s); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
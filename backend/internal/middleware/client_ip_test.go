@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		trusted []string
+		remote  string
+		headers map[string][]string
+		want    string
+	}{
+		{
+			name:    "headers ignored without trusted proxies",
+			remote:  "203.0.113.7:5000",
+			headers: map[string][]string{"X-Forwarded-For": {"1.2.3.4"}},
+			want:    "203.0.113.7",
+		},
+		{
+			name:    "headers ignored from untrusted peer",
+			trusted: []string{"10.0.0.0/8"},
+			remote:  "203.0.113.7:5000",
+			headers: map[string][]string{"X-Forwarded-For": {"1.2.3.4"}},
+			want:    "203.0.113.7",
+		},
+		{
+			name:    "spoofed leftmost entry skipped",
+			trusted: []string{"10.0.0.0/8"},
+			remote:  "10.0.0.1:5000",
+			headers: map[string][]string{"X-Forwarded-For": {"1.2.3.4, 198.51.100.9, 10.0.0.2"}},
+			want:    "198.51.100.9",
+		},
+		{
+			name:    "multiple header lines",
+			trusted: []string{"10.0.0.1"},
+			remote:  "10.0.0.1:5000",
+			headers: map[string][]string{"X-Forwarded-For": {"1.2.3.4", "198.51.100.9"}},
+			want:    "198.51.100.9",
+		},
+		{
+			name:    "all hops trusted",
+			trusted: []string{"10.0.0.0/8"},
+			remote:  "10.0.0.1:5000",
+			headers: map[string][]string{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}},
+			want:    "10.0.0.3",
+		},
+		{
+			name:    "garbage hop stops the walk",
+			trusted: []string{"10.0.0.0/8"},
+			remote:  "10.0.0.1:5000",
+			headers: map[string][]string{"X-Forwarded-For": {"1.2.3.4, nonsense, 10.0.0.2"}},
+			want:    "10.0.0.2",
+		},
+		{
+			name:    "other headers are not trusted",
+			trusted: []string{"10.0.0.0/8"},
+			remote:  "10.0.0.1:5000",
+			headers: map[string][]string{"X-Real-IP": {"1.2.3.4"}},
+			want:    "10.0.0.1",
+		},
+		{
+			name:    "x-real-ip",
+			header:  "x-real-ip",
+			trusted: []string{"10.0.0.0/8"},
+			remote:  "10.0.0.1:5000",
+			headers: map[string][]string{"X-Real-IP": {"198.51.100.9"}, "X-Forwarded-For": {"1.2.3.4"}},
+			want:    "198.51.100.9",
+		},
+		{
+			name:    "forwarded with ipv6 and ports",
+			header:  "Forwarded",
+			trusted: []string{"10.0.0.0/8", "fd00::/8"},
+			remote:  "[fd00::1]:443",
+			headers: map[string][]string{"Forwarded": {
+				`for=1.2.3.4, For="[2001:db8:cafe::17]:4711";proto=https`,
+				`for=10.0.0.2:80;by=10.0.0.1`,
+			}},
+			want: "2001:db8:cafe::17",
+		},
+		{
+			name:    "forwarded obfuscated hop",
+			header:  "Forwarded",
+			trusted: []string{"10.0.0.0/8"},
+			remote:  "10.0.0.1:5000",
+			headers: map[string][]string{"Forwarded": {`for=1.2.3.4, for=_hidden, for=10.0.0.2`}},
+			want:    "10.0.0.2",
+		},
+		{
+			name:    "ipv4-mapped addresses unmapped",
+			trusted: []string{"10.0.0.0/8"},
+			remote:  "[::ffff:10.0.0.1]:5000",
+			headers: map[string][]string{"X-Forwarded-For": {"::ffff:198.51.100.9"}},
+			want:    "198.51.100.9",
+		},
+	}
+
+	t.Cleanup(func() { InitClientIP("", nil) })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := InitClientIP(tt.header, tt.trusted); err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remote
+			for name, values := range tt.headers {
+				for _, v := range values {
+					req.Header.Add(name, v)
+				}
+			}
+
+			var got string
+			RealIP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = ClientIP(r)
+			})).ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Fatalf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestInitClientIPRejectsBadConfig(t *testing.T) {
+	t.Cleanup(func() { InitClientIP("", nil) })
+	if err := InitClientIP("X-Client", nil); err == nil {
+		t.Fatal("expected unsupported header to be rejected")
+	}
+	if err := InitClientIP("", []string{"10.0.0.0/33"}); err == nil {
+		t.Fatal("expected invalid CIDR to be rejected")
+	}
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/brij-812/HyperLinkOS/internal/cache"
+	"github.com/brij-812/HyperLinkOS/internal/metrics"
+	"github.com/brij-812/HyperLinkOS/internal/ratelimit"
+)
+
+// EnumerationConfig tunes EnumerationGuard. An IP is blocked for BlockFor
+// once, within one Window, it has at least MinMisses redirects answered
+// 404/410 and those make up at least MissRatio of its redirects.
+type EnumerationConfig struct {
+	Window    time.Duration
+	MinMisses int
+	MissRatio float64
+	BlockFor  time.Duration
+}
+
+// DefaultEnumerationConfig returns the limits used for unset fields.
+func DefaultEnumerationConfig() EnumerationConfig {
+	return EnumerationConfig{
+		Window:    time.Minute,
+		MinMisses: 20,
+		MissRatio: 0.5,
+		BlockFor:  15 * time.Minute,
+	}
+}
+
+var (
+	enumCache  cache.Cache = cache.Noop{}
+	enumConfig             = DefaultEnumerationConfig()
+)
+
+// InitEnumerationGuard enables EnumerationGuard with counters in c; use a
+// shared cache so a block applies on every replica.
+func InitEnumerationGuard(c cache.Cache, cfg EnumerationConfig) {
+	def := DefaultEnumerationConfig()
+	if cfg.Window < time.Second {
+		cfg.Window = def.Window
+	}
+	if cfg.MinMisses <= 0 {
+		cfg.MinMisses = def.MinMisses
+	}
+	if cfg.MissRatio <= 0 {
+		cfg.MissRatio = def.MissRatio
+	}
+	if cfg.BlockFor <= 0 {
+		cfg.BlockFor = def.BlockFor
+	}
+	enumCache = cache.OrNoop(c)
+	enumConfig = cfg
+}
+
+// EnumerationGuard blocks clients that look like they are scanning for
+// valid short codes, i.e. mostly hit unknown or expired links.
+func EnumerationGuard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := ClientIP(r)
+		blockKey := "enum:block:" + ip
+		now := clock()
+
+		if raw, ok := enumCache.Get(r.Context(), blockKey); ok {
+			until, _ := strconv.ParseInt(raw, 10, 64)
+			if wait := time.Unix(until, 0).Sub(now); wait > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(wait)))
+				metrics.RateLimitRejections.WithLabelValues("enumeration", ratelimit.PrincipalIP).Inc()
+				http.Error(w, "too many requests for unknown short links", http.StatusTooManyRequests)
+				return
+			}
+		}
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+
+		window := now.Unix() / int64(enumConfig.Window.Seconds())
+		prefix := fmt.Sprintf("enum:%s:%d", ip, window)
+		ttl := 2 * enumConfig.Window
+
+		// Counter errors only mean the cache is down; the request is already served.
+		total, err := enumCache.Incr(r.Context(), prefix+":total", ttl)
+		if err != nil || (sw.status != http.StatusNotFound && sw.status != http.StatusGone) {
+			return
+		}
+		misses, err := enumCache.Incr(r.Context(), prefix+":miss", ttl)
+		if err != nil {
+			return
+		}
+
+		if misses >= int64(enumConfig.MinMisses) && float64(misses)/float64(total) >= enumConfig.MissRatio {
+			until := now.Add(enumConfig.BlockFor)
+			enumCache.Set(r.Context(), blockKey, strconv.FormatInt(until.Unix(), 10), enumConfig.BlockFor)
+			slog.WarnContext(r.Context(), "🚫 Blocking IP: redirects hit unknown or expired codes", "ip", ip, "block_for", enumConfig.BlockFor, "misses", misses, "total", total)
+		}
+	})
+}
+
+// statusWriter records the status code written by the wrapped handler.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/brij-812/HyperLinkOS/internal/cache"
+)
+
+func TestEnumerationGuard(t *testing.T) {
+	now := useLocalRateLimit(t)
+	InitEnumerationGuard(cache.NewLocal(0), EnumerationConfig{MinMisses: 5, MissRatio: 0.5, BlockFor: time.Minute})
+	t.Cleanup(func() { InitEnumerationGuard(nil, EnumerationConfig{}) })
+
+	handler := EnumerationGuard(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/good" {
+			http.Redirect(w, r, "https://example.com", http.StatusFound)
+			return
+		}
+		http.Error(w, "short URL not found", http.StatusNotFound)
+	}))
+	get := func(ip, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = ip
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// a client with a few typos among many valid clicks is left alone
+	for i := 0; i < 20; i++ {
+		get("10.0.0.1:1", "/good")
+	}
+	for i := 0; i < 5; i++ {
+		get("10.0.0.1:1", "/typo")
+	}
+	if w := get("10.0.0.1:1", "/good"); w.Code != http.StatusFound {
+		t.Fatalf("expected legitimate client to pass, got %d", w.Code)
+	}
+
+	// a scanner is blocked after MinMisses unknown codes
+	for i := 0; i < 5; i++ {
+		if w := get("10.0.0.2:1", "/guess"); w.Code != http.StatusNotFound {
+			t.Fatalf("scan %d: expected 404, got %d", i, w.Code)
+		}
+	}
+	w := get("10.0.0.2:1", "/good")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Fatalf("expected 429 with Retry-After 60, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// the block lifts after BlockFor
+	*now = now.Add(time.Minute)
+	if w := get("10.0.0.2:1", "/good"); w.Code != http.StatusFound {
+		t.Fatalf("expected block to expire, got %d", w.Code)
+	}
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brij-812/HyperLinkOS/internal/logging"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestRequestIDAndAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	h, err := logging.NewHandler(&buf, "info", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := slog.Default()
+	slog.SetDefault(slog.New(h))
+	defer slog.SetDefault(prev)
+
+	r := chi.NewRouter()
+	r.Use(RequestID, AccessLog)
+	r.Get("/url/{code}/stats", func(w http.ResponseWriter, r *http.Request) {
+		slog.InfoContext(r.Context(), "handler ran")
+		w.Write([]byte("ok"))
+	})
+
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"caller ID reused", "lb-7f3a:42", true},
+		{"missing ID generated", "", false},
+		{"unsafe ID replaced", "abc\r\nInjected: 1", false},
+		{"overlong ID replaced", strings.Repeat("a", maxRequestIDLength+1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			req := httptest.NewRequest(http.MethodGet, "/url/abc/stats", nil)
+			if tt.incoming != "" {
+				req.Header.Set(HeaderRequestID, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(HeaderRequestID)
+			if tt.keep && id != tt.incoming {
+				t.Fatalf("expected request ID %q to be reused, got %q", tt.incoming, id)
+			}
+			if !tt.keep && (id == tt.incoming || len(id) != 32) {
+				t.Fatalf("expected a generated request ID, got %q", id)
+			}
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if len(lines) != 2 {
+				t.Fatalf("expected a handler line and an access line, got %q", buf.String())
+			}
+			for _, line := range lines {
+				var rec map[string]any
+				if err := json.Unmarshal([]byte(line), &rec); err != nil {
+					t.Fatalf("log line is not JSON: %q", line)
+				}
+				if rec["request_id"] != id {
+					t.Errorf("expected request_id %q in %q", id, line)
+				}
+			}
+
+			var access map[string]any
+			json.Unmarshal([]byte(lines[1]), &access)
+			if access["msg"] != "request" || access["status"] != float64(200) ||
+				access["route"] != "/url/{code}/stats" || access["path"] != "/url/abc/stats" {
+				t.Errorf("unexpected access log line %q", lines[1])
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brij-812/HyperLinkOS/internal/metrics"
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsLabelsByRoutePattern(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(Metrics)
+	r.Get("/url/{code}/stats", func(w http.ResponseWriter, r *http.Request) {})
+	r.Delete("/url/{code}", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	})
+
+	count := func(method, route, status string) float64 {
+		return testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(method, route, status))
+	}
+	statsBefore := count(http.MethodGet, "/url/{code}/stats", "200")
+	deleteBefore := count(http.MethodDelete, "/url/{code}", "404")
+	unmatchedBefore := count(http.MethodGet, unmatchedRoute, "404")
+
+	for _, req := range []struct{ method, path string }{
+		{http.MethodGet, "/url/abc/stats"},
+		{http.MethodGet, "/url/xyz/stats"},
+		{http.MethodDelete, "/url/abc"},
+		{http.MethodGet, "/wp-login.php"},
+	} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.path, nil))
+	}
+
+	if got := count(http.MethodGet, "/url/{code}/stats", "200") - statsBefore; got != 2 {
+		t.Errorf("expected 2 stats requests under one pattern, got %v", got)
+	}
+	if got := count(http.MethodDelete, "/url/{code}", "404") - deleteBefore; got != 1 {
+		t.Errorf("expected the handler's 404 to be recorded, got %v", got)
+	}
+	if got := count(http.MethodGet, unmatchedRoute, "404") - unmatchedBefore; got != 1 {
+		t.Errorf("expected unknown paths to share the %q label, got %v", unmatchedRoute, got)
+	}
+	if n := testutil.CollectAndCount(metrics.HTTPRequests, "hyperlinkos_http_requests_total"); n == 0 {
+		t.Error("expected request series to be exported")
+	}
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+	}()
+
+	var handlerSpan trace.SpanContext
+	r := chi.NewRouter()
+	r.Use(Tracing)
+	r.Get("/url/{code}/stats", func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	const parent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := httptest.NewRequest(http.MethodGet, "/url/abc/stats", nil)
+	req.Header.Set("traceparent", parent)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nope", nil))
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	s := spans[0]
+	if s.Name() != "GET /url/{code}/stats" {
+		t.Errorf("expected span named after the route, got %q", s.Name())
+	}
+	if got := s.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected the incoming trace to be continued, got trace %s", got)
+	}
+	if s.Parent().SpanID().String() != "00f067aa0ba902b7" || !s.Parent().IsRemote() {
+		t.Errorf("expected the remote caller as parent, got %v", s.Parent())
+	}
+	if handlerSpan.SpanID() != s.SpanContext().SpanID() {
+		t.Error("expected the handler context to carry the server span")
+	}
+	if s.Status().Code != codes.Error {
+		t.Errorf("expected a 500 to mark the span as failed, got %v", s.Status())
+	}
+	attrs := map[string]string{}
+	for _, kv := range s.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	if attrs["http.route"] != "/url/{code}/stats" || attrs["http.response.status_code"] != "500" {
+		t.Errorf("unexpected attributes %v", attrs)
+	}
+
+	if spans[1].Name() != "GET unmatched" || spans[1].Parent().IsValid() {
+		t.Errorf("expected a root span for the unmatched route, got %q parent %v", spans[1].Name(), spans[1].Parent())
+	}
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Scopes an API key can be granted. Browser sessions implicitly hold all of them.
+const (
+	ScopeLinksWrite  = "links:write"
+	ScopeLinksRead   = "links:read"
+	ScopeMetricsRead = "metrics:read"
+)
+
+// AllScopes lists every grantable scope.
+var AllScopes = []string{ScopeLinksWrite, ScopeLinksRead, ScopeMetricsRead}
+
+// APIKey describes a stored personal API key (never the secret itself).
+type APIKey struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// APIKeyOwner is what a valid API key authenticates as.
+type APIKeyOwner struct {
+	KeyID  int
+	UserID int
+	Plan   string
+	Scopes []string
+}
+
+// Request body for creating an API key
+type CreateAPIKeyRequest struct {
+	Name       string   `json:"name"`
+	Scopes     []string `json:"scopes,omitempty"` // defaults to all scopes
+	ExpiryDays int      `json:"expiry_days,omitempty"`
+}
+
+// Response body for a new API key; Key is only ever returned once.
+type CreateAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
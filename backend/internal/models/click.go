@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// ClickEvent is a single redirect of a short link.
+type ClickEvent struct {
+	Code      string    `json:"code"`
+	ClickedAt time.Time `json:"clicked_at"`
+	Referrer  string    `json:"referrer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	IPHash    string    `json:"ip_hash,omitempty"` // salted hash, raw IPs are never stored
+	Country   string    `json:"country,omitempty"` // placeholder until geo lookup exists
+}
+
+// LinkStats summarizes clicks for one link.
+type LinkStats struct {
+	Code        string `json:"code"`
+	TotalClicks int    `json:"total_clicks"`
+	// UniqueVisitors adds up each UTC day's unique visitors, the figure the
+	// daily rollups keep; a visitor returning on another day counts again.
+	UniqueVisitors int           `json:"unique_visitors"`
+	Bucket         string        `json:"bucket"`
+	Since          time.Time     `json:"since"`
+	Series         []StatsBucket `json:"series"`
+}
+
+// StatsBucket is one point of a time-bucketed click series.
+type StatsBucket struct {
+	Start  time.Time `json:"start"`
+	Clicks int       `json:"clicks"`
+	Unique int       `json:"unique"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Domain is a custom hostname a user can issue short links on once verified.
+type Domain struct {
+	ID                int        `json:"id"`
+	UserID            int        `json:"-"`
+	Hostname          string     `json:"hostname"`
+	VerificationToken string     `json:"verification_token"`
+	VerifiedAt        *time.Time `json:"verified_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// Verified reports whether DNS ownership has been confirmed.
+func (d *Domain) Verified() bool {
+	return d.VerifiedAt != nil
+}
+
+// Request body for adding a custom domain
+type CreateDomainRequest struct {
+	Hostname string `json:"hostname"`
+}
+
+// Response body describing the DNS record that proves ownership
+type DomainResponse struct {
+	Domain
+	TXTRecordName  string `json:"txt_record_name"`
+	TXTRecordValue string `json:"txt_record_value"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// RefreshToken is a stored refresh token; only the hash of the raw value is
+// kept. Tokens issued from one login share a FamilyID.
+type RefreshToken struct {
+	ID        int64
+	UserID    int
+	FamilyID  string
+	TokenHash string
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+
+	// Email and Plan of the owner, filled in by lookups.
+	Email string
+	Plan  string
+}
@@ -4,12 +4,79 @@ import "time"
 
 // Request body for shortening a URL
 type ShortenRequest struct {
-	URL        string `json:"url"`
-	ExpiryDays int    `json:"expiry_days,omitempty"`
+	URL         string `json:"url"`
+	ExpiryDays  int    `json:"expiry_days,omitempty"`
+	Alias       string `json:"alias,omitempty"`        // optional custom short code
+	Shared      bool   `json:"shared,omitempty"`       // let other users reuse this link
+	ReuseShared bool   `json:"reuse_shared,omitempty"` // reuse someone's shared link for the same URL
+	Domain      string `json:"domain,omitempty"`       // verified custom domain to issue the link on
+	Password    string `json:"password,omitempty"`     // visitors must enter it before being redirected
+	MaxClicks   int    `json:"max_clicks,omitempty"`   // the link dies after this many redirects (1 = one-time)
+	// ActivatesAt schedules the link: it only starts redirecting at this time.
+	ActivatesAt *time.Time `json:"activates_at,omitempty"`
 }
 
 // Response body for a shortened URL
 type ShortenResponse struct {
-	ShortURL  string     `json:"short_url"`
+	ShortURL    string     `json:"short_url"`
+	ActivatesAt *time.Time `json:"activates_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	MaxClicks   int        `json:"max_clicks,omitempty"`
+}
+
+// Link is a stored short link owned by a single user.
+type Link struct {
+	Code      string     `json:"code"`
+	LongURL   string     `json:"long_url"`
+	UserID    int        `json:"user_id"`
+	Shared    bool       `json:"shared"`
+	DomainID  int        `json:"-"`                // 0 = default public domain
+	Domain    string     `json:"domain,omitempty"` // custom domain hostname, read-only
+	CreatedAt time.Time  `json:"created_at"`
 	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// PasswordHash is the bcrypt hash gating the link; empty = public.
+	PasswordHash string `json:"-"`
+	// ClicksLeft is the remaining redirects of a click-limited link; nil = unlimited.
+	ClicksLeft *int `json:"clicks_left,omitempty"`
+	// ActivatesAt is when a scheduled link starts redirecting; nil = immediately.
+	ActivatesAt *time.Time `json:"activates_at,omitempty"`
+}
+
+// Protected reports whether visitors need a password.
+func (l *Link) Protected() bool {
+	return l.PasswordHash != ""
+}
+
+// Exhausted reports whether a click-limited link has no clicks left.
+func (l *Link) Exhausted() bool {
+	return l.ClicksLeft != nil && *l.ClicksLeft <= 0
+}
+
+// Pending reports whether a scheduled link hasn't gone live yet.
+func (l *Link) Pending(now time.Time) bool {
+	return l.ActivatesAt != nil && now.Before(*l.ActivatesAt)
+}
+
+// Expired reports whether the link's expiry has passed.
+func (l *Link) Expired(now time.Time) bool {
+	return l.ExpiresAt != nil && now.After(*l.ExpiresAt)
+}
+
+// Request body for PATCH /url/{code}; omitted fields are left unchanged.
+// expiry_days: 0 removes the expiry. Only one of expires_at / expiry_days may be set.
+type UpdateLinkRequest struct {
+	LongURL    *string    `json:"long_url,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	ExpiryDays *int       `json:"expiry_days,omitempty"`
+	Shared     *bool      `json:"shared,omitempty"`
+}
+
+// LinkUpdate is the repository-level form of a PATCH; nil fields are kept.
+type LinkUpdate struct {
+	LongURL *string
+	Shared  *bool
+	// SetExpiry replaces the expiry with ExpiresAt (nil = never expires).
+	SetExpiry bool
+	ExpiresAt *time.Time
 }
@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/brij-812/HyperLinkOS/internal/cache"
+)
+
+// Local evaluates limits in process. Counters live in an LRU cache, so
+// memory stays bounded however many clients show up.
+type Local struct {
+	mu    sync.Mutex
+	store *cache.Local
+}
+
+func NewLocal(maxKeys int) *Local {
+	return &Local{store: cache.NewLocal(maxKeys)}
+}
+
+func (l *Local) Allow(ctx context.Context, key string, p Policy, now time.Time) (Decision, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if p.Algorithm == TokenBucket {
+		return l.tokenBucket(ctx, key, p, now), nil
+	}
+
+	index, elapsed := windowStart(now, p.Window)
+	currKey := fmt.Sprintf("%s:%d", key, index)
+	s := slidingWindow{elapsed: elapsed}
+	s.curr = l.count(ctx, currKey)
+	s.prev = l.count(ctx, fmt.Sprintf("%s:%d", key, index-1))
+
+	allowed := s.allowed(p.Limit)
+	if allowed {
+		s.curr++
+		l.store.Set(ctx, currKey, strconv.FormatInt(s.curr, 10), 2*p.Window)
+	}
+	return s.decision(p, allowed), nil
+}
+
+func (l *Local) count(ctx context.Context, key string) int64 {
+	raw, ok := l.store.Get(ctx, key)
+	if !ok {
+		return 0
+	}
+	n, _ := strconv.ParseInt(raw, 10, 64)
+	return n
+}
+
+func (l *Local) tokenBucket(ctx context.Context, key string, p Policy, now time.Time) Decision {
+	b := tokenBucket{tokens: float64(p.Limit), last: now}
+	if raw, ok := l.store.Get(ctx, key); ok {
+		var tokens float64
+		var last int64
+		if _, err := fmt.Sscanf(raw, "%g %d", &tokens, &last); err == nil {
+			b = tokenBucket{tokens: tokens, last: time.Unix(0, last)}
+		}
+	}
+
+	d := b.take(p, now)
+	l.store.Set(ctx, key, fmt.Sprintf("%g %d", b.tokens, b.last.UnixNano()), d.Reset+time.Second)
+	return d
+}
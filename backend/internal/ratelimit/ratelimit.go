@@ -0,0 +1,165 @@
+// Package ratelimit evaluates request limits with sliding-window or
+// token-bucket algorithms, either atomically in Redis or in process.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Algorithms a Policy can use.
+const (
+	// SlidingWindow blends the previous fixed window's count, weighted by
+	// how much of it still overlaps the sliding window, with the current one.
+	SlidingWindow = "sliding_window"
+	// TokenBucket allows bursts of up to Limit requests and refills
+	// Limit tokens per Window.
+	TokenBucket = "token_bucket"
+)
+
+// Policy is one limit: Limit requests per Window.
+type Policy struct {
+	Algorithm string
+	Limit     int
+	Window    time.Duration
+}
+
+// Validate reports a policy that can never be evaluated.
+func (p Policy) Validate() error {
+	switch {
+	case p.Algorithm != SlidingWindow && p.Algorithm != TokenBucket:
+		return fmt.Errorf("unknown algorithm %q (want %s or %s)", p.Algorithm, SlidingWindow, TokenBucket)
+	case p.Limit <= 0:
+		return fmt.Errorf("limit must be positive, got %d", p.Limit)
+	case p.Window < time.Second:
+		return fmt.Errorf("window must be at least 1s, got %s", p.Window)
+	}
+	return nil
+}
+
+// Decision is the outcome of one Allow call.
+type Decision struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long a rejected caller should wait; zero when allowed.
+	RetryAfter time.Duration
+	// Reset is when the limit is fully available again.
+	Reset time.Duration
+}
+
+// Limiter counts a request against key under p.
+type Limiter interface {
+	Allow(ctx context.Context, key string, p Policy, now time.Time) (Decision, error)
+}
+
+// slidingWindow holds the counters of one sliding-window evaluation.
+type slidingWindow struct {
+	curr, prev int64   // requests in the current / previous fixed window
+	elapsed    float64 // fraction of the current window that has passed
+}
+
+func windowStart(now time.Time, window time.Duration) (index int64, elapsed float64) {
+	ms := now.UnixMilli()
+	w := window.Milliseconds()
+	return ms / w, float64(ms%w) / float64(w)
+}
+
+func (s slidingWindow) weighted() float64 {
+	return float64(s.prev)*(1-s.elapsed) + float64(s.curr)
+}
+
+// allowed reports whether one more request fits under limit.
+func (s slidingWindow) allowed(limit int) bool {
+	return s.weighted()+1 <= float64(limit)
+}
+
+// decision builds the Decision after evaluation; curr already includes
+// the request when it was allowed.
+func (s slidingWindow) decision(p Policy, allowed bool) Decision {
+	d := Decision{
+		Allowed:   allowed,
+		Limit:     p.Limit,
+		Remaining: max(0, int(math.Floor(float64(p.Limit)-s.weighted()))),
+		Reset:     time.Duration((1 - s.elapsed) * float64(p.Window)),
+	}
+	if allowed {
+		return d
+	}
+
+	// Find the first moment the weighted count drops to limit-1.
+	free := float64(p.Limit - 1)
+	var wait float64 // in windows
+	if float64(s.curr) > free {
+		// Not before the next window, where today's count becomes "previous".
+		wait = 1 - s.elapsed
+		if s.curr > 0 {
+			wait += max(0, 1-free/float64(s.curr))
+		}
+	} else if s.prev > 0 {
+		wait = max(0, 1-(free-float64(s.curr))/float64(s.prev)-s.elapsed)
+	}
+	d.RetryAfter = time.Duration(wait * float64(p.Window))
+	return d
+}
+
+// tokenBucket holds the state of one token-bucket evaluation.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take refills the bucket up to now and spends one token if available.
+func (b *tokenBucket) take(p Policy, now time.Time) Decision {
+	rate := float64(p.Limit) / float64(p.Window) // tokens per nanosecond
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(float64(p.Limit), b.tokens+float64(elapsed)*rate)
+	}
+	b.last = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return bucketDecision(p, b.tokens, allowed)
+}
+
+// bucketDecision describes a bucket left with tokens after evaluation.
+func bucketDecision(p Policy, tokens float64, allowed bool) Decision {
+	rate := float64(p.Limit) / float64(p.Window) // tokens per nanosecond
+	d := Decision{
+		Allowed:   allowed,
+		Limit:     p.Limit,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((float64(p.Limit) - tokens) / rate),
+	}
+	if !allowed {
+		d.RetryAfter = time.Duration((1 - tokens) / rate)
+	}
+	return d
+}
+
+// Principal kinds a Group can define limits for. Plan tiers use
+// PlanPrefix + the plan name, e.g. "plan:pro".
+const (
+	PrincipalIP     = "ip"
+	PrincipalUser   = "user"
+	PrincipalAPIKey = "api_key"
+	PlanPrefix      = "plan:"
+)
+
+// ValidPrincipal reports whether kind is a principal kind a Group may use.
+func ValidPrincipal(kind string) bool {
+	switch kind {
+	case PrincipalIP, PrincipalUser, PrincipalAPIKey:
+		return true
+	}
+	plan, ok := strings.CutPrefix(kind, PlanPrefix)
+	return ok && plan != ""
+}
+
+// Group holds the policies of one route group, keyed by principal kind.
+type Group map[string]Policy
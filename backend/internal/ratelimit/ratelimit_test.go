@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func limiters(t *testing.T) map[string]Limiter {
+	m := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: m.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return map[string]Limiter{"local": NewLocal(0), "redis": NewRedis(rdb)}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	p := Policy{Algorithm: SlidingWindow, Limit: 3, Window: time.Minute}
+	start := time.Unix(1_700_000_040, 0) // exactly on a window boundary
+
+	for name, l := range limiters(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			for i := 1; i <= 3; i++ {
+				d, err := l.Allow(ctx, "k", p, start)
+				if err != nil || !d.Allowed || d.Remaining != 3-i {
+					t.Fatalf("request %d: %+v %v", i, d, err)
+				}
+			}
+			d, _ := l.Allow(ctx, "k", p, start)
+			if d.Allowed || d.RetryAfter != 2*time.Minute-40*time.Second {
+				t.Fatalf("expected rejection with retry 1m20s, got %+v", d)
+			}
+
+			// 1m20s later the previous window only weighs 2/3: 3*(1/3) = 1 + 1 fits
+			d, _ = l.Allow(ctx, "k", p, start.Add(80*time.Second))
+			if !d.Allowed {
+				t.Fatalf("expected request after Retry-After to pass, got %+v", d)
+			}
+		})
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	p := Policy{Algorithm: TokenBucket, Limit: 2, Window: 10 * time.Second}
+	now := time.Unix(1_700_000_000, 0)
+
+	for name, l := range limiters(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			for i := 0; i < 2; i++ {
+				if d, err := l.Allow(ctx, "bucket", p, now); err != nil || !d.Allowed {
+					t.Fatalf("burst request %d: %+v %v", i, d, err)
+				}
+			}
+			d, _ := l.Allow(ctx, "bucket", p, now)
+			if d.Allowed || d.RetryAfter != 5*time.Second || d.Reset != 10*time.Second {
+				t.Fatalf("expected rejection with retry 5s, got %+v", d)
+			}
+			if d, _ := l.Allow(ctx, "bucket", p, now.Add(5*time.Second)); !d.Allowed || d.Remaining != 0 {
+				t.Fatalf("expected one token after 5s, got %+v", d)
+			}
+		})
+	}
+}
+
+func TestRedisIsAtomic(t *testing.T) {
+	l := limiters(t)["redis"]
+	now := time.Unix(1_700_000_000, 0)
+
+	for _, algo := range []string{SlidingWindow, TokenBucket} {
+		p := Policy{Algorithm: algo, Limit: 20, Window: time.Hour}
+		var allowed atomic.Int64
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if d, err := l.Allow(context.Background(), "race:"+algo, p, now); err == nil && d.Allowed {
+					allowed.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		if allowed.Load() != 20 {
+			t.Errorf("%s: expected exactly 20 allowed, got %d", algo, allowed.Load())
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// slidingWindowScript checks and increments in one step, so concurrent
+// requests can't all read the same count and slip past the limit.
+// KEYS: current window counter, previous window counter.
+// ARGV: limit, weight of the previous window, counter TTL (ms).
+var slidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local weight = tonumber(ARGV[2])
+local curr = tonumber(redis.call('GET', KEYS[1]) or '0')
+local prev = tonumber(redis.call('GET', KEYS[2]) or '0')
+if prev * weight + curr + 1 > limit then
+	return {0, curr, prev}
+end
+curr = redis.call('INCR', KEYS[1])
+if curr == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[3])
+end
+return {1, curr, prev}
+`)
+
+// tokenBucketScript refills and spends a token in one step.
+// KEYS: bucket hash. ARGV: capacity, tokens per ms, now (ms).
+// Tokens are returned as a string to keep the fraction.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+if now > ts then
+	tokens = math.min(capacity, tokens + (now - ts) * rate)
+	ts = now
+end
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', ts)
+redis.call('PEXPIRE', KEYS[1], math.ceil((capacity - tokens) / rate) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// Redis evaluates limits in Redis so all replicas share them.
+type Redis struct {
+	rdb *redis.Client
+}
+
+func NewRedis(rdb *redis.Client) *Redis {
+	return &Redis{rdb: rdb}
+}
+
+func (l *Redis) Allow(ctx context.Context, key string, p Policy, now time.Time) (Decision, error) {
+	// The hash tag keeps all keys of one client in the same cluster slot.
+	key = "{" + key + "}"
+
+	if p.Algorithm == TokenBucket {
+		return l.tokenBucket(ctx, key, p, now)
+	}
+
+	index, elapsed := windowStart(now, p.Window)
+	res, err := slidingWindowScript.Run(ctx, l.rdb,
+		[]string{fmt.Sprintf("%s:%d", key, index), fmt.Sprintf("%s:%d", key, index-1)},
+		p.Limit, strconv.FormatFloat(1-elapsed, 'f', -1, 64), (2 * p.Window).Milliseconds(),
+	).Int64Slice()
+	if err != nil {
+		return Decision{}, err
+	}
+	s := slidingWindow{curr: res[1], prev: res[2], elapsed: elapsed}
+	return s.decision(p, res[0] == 1), nil
+}
+
+func (l *Redis) tokenBucket(ctx context.Context, key string, p Policy, now time.Time) (Decision, error) {
+	rate := float64(p.Limit) / float64(p.Window.Milliseconds())
+	res, err := tokenBucketScript.Run(ctx, l.rdb, []string{key},
+		p.Limit, strconv.FormatFloat(rate, 'f', -1, 64), now.UnixMilli(),
+	).Slice()
+	if err != nil {
+		return Decision{}, err
+	}
+	if len(res) != 2 {
+		return Decision{}, fmt.Errorf("token bucket script: unexpected reply %v", res)
+	}
+	allowed, _ := res[0].(int64)
+	raw, _ := res[1].(string)
+	tokens, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return Decision{}, fmt.Errorf("token bucket script: %w", err)
+	}
+
+	return bucketDecision(p, tokens, allowed == 1), nil
+}
@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"slices"
+	"time"
+
+	"github.com/brij-812/HyperLinkOS/internal/models"
+	"github.com/lib/pq"
+)
+
+// APIKeyPrefix marks raw keys so they are easy to recognise in configs and secret scanners.
+const APIKeyPrefix = "hlk_"
+
+// APIKeyStore manages personal API keys. Only hashes of the raw keys are stored.
+type APIKeyStore interface {
+	// CreateAPIKey generates a new key for userID and returns its metadata and the raw key.
+	CreateAPIKey(ctx context.Context, userID int, name string, scopes []string, expiresAt *time.Time) (*models.APIKey, string, error)
+	// ListAPIKeys returns all keys of a user, newest first, including revoked ones.
+	ListAPIKeys(ctx context.Context, userID int) ([]models.APIKey, error)
+	// RevokeAPIKey disables a key owned by userID; ErrNotFound if no live key matched.
+	RevokeAPIKey(ctx context.Context, userID, id int) error
+	// LookupAPIKey resolves a raw key to its owner, plan and scopes, recording
+	// its use. Unknown, revoked and expired keys are ErrNotFound.
+	LookupAPIKey(ctx context.Context, raw string) (models.APIKeyOwner, error)
+}
+
+// newAPIKey returns a random raw key and the metadata stored for it.
+func newAPIKey(name string, scopes []string, expiresAt *time.Time) (*models.APIKey, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", err
+	}
+	raw := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	if scopes == nil {
+		scopes = []string{} // stored as an empty array, not NULL
+	}
+	return &models.APIKey{
+		Name:      name,
+		Prefix:    raw[:len(APIKeyPrefix)+8],
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}, raw, nil
+}
+
+func hashAPIKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// ---------------------------------------------------------------------------
+// Postgres
+// ---------------------------------------------------------------------------
+
+func (r *PostgresRepo) CreateAPIKey(ctx context.Context, userID int, name string, scopes []string, expiresAt *time.Time) (*models.APIKey, string, error) {
+	key, raw, err := newAPIKey(name, scopes, expiresAt)
+	if err != nil {
+		return nil, "", err
+	}
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, userID, name, key.Prefix, hashAPIKey(raw), pq.Array(key.Scopes), expiresAt).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return nil, "", dbError(ctx, "CreateAPIKey", err)
+	}
+	return key, raw, nil
+}
+
+func (r *PostgresRepo) ListAPIKeys(ctx context.Context, userID int) ([]models.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, name, prefix, scopes, expires_at, last_used_at, revoked_at, created_at
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, dbError(ctx, "ListAPIKeys", err)
+	}
+	return scanAPIKeys(ctx, rows, dbError, func(s *[]string) any { return pq.Array(s) })
+}
+
+// scanAPIKeys reads ListAPIKeys rows. mapErr is dbError or sqliteError, and
+// scopes wraps the destination for the backend's scopes encoding.
+func scanAPIKeys(ctx context.Context, rows *sql.Rows, mapErr func(context.Context, string, error) error, scopes func(*[]string) any) ([]models.APIKey, error) {
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		var k models.APIKey
+		var expiresAt, lastUsedAt, revokedAt sql.NullTime
+		if err := rows.Scan(&k.ID, &k.Name, &k.Prefix, scopes(&k.Scopes), &expiresAt, &lastUsedAt, &revokedAt, &k.CreatedAt); err != nil {
+			return nil, mapErr(ctx, "ListAPIKeys scan", err)
+		}
+		k.ExpiresAt = nullTimePtr(expiresAt)
+		k.LastUsedAt = nullTimePtr(lastUsedAt)
+		k.RevokedAt = nullTimePtr(revokedAt)
+		keys = append(keys, k)
+	}
+	return keys, mapErr(ctx, "ListAPIKeys", rows.Err())
+}
+
+func (r *PostgresRepo) RevokeAPIKey(ctx context.Context, userID, id int) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE api_keys SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, id, userID)
+	if err != nil {
+		return dbError(ctx, "RevokeAPIKey", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepo) LookupAPIKey(ctx context.Context, raw string) (models.APIKeyOwner, error) {
+	var owner models.APIKeyOwner
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE api_keys k SET last_used_at = NOW()
+		FROM users u
+		WHERE k.key_hash = $1
+		  AND u.id = k.user_id
+		  AND k.revoked_at IS NULL
+		  AND (k.expires_at IS NULL OR k.expires_at > NOW())
+		RETURNING k.id, k.user_id, u.plan, k.scopes
+	`, hashAPIKey(raw)).Scan(&owner.KeyID, &owner.UserID, &owner.Plan, pq.Array(&owner.Scopes))
+	if err != nil {
+		return models.APIKeyOwner{}, dbError(ctx, "LookupAPIKey", err)
+	}
+	return owner, nil
+}
+
+// ---------------------------------------------------------------------------
+// Memory
+// ---------------------------------------------------------------------------
+
+type memoryAPIKey struct {
+	models.APIKey
+	userID int
+	hash   string
+}
+
+func (r *MemoryRepo) CreateAPIKey(_ context.Context, userID int, name string, scopes []string, expiresAt *time.Time) (*models.APIKey, string, error) {
+	key, raw, err := newAPIKey(name, scopes, expiresAt)
+	if err != nil {
+		return nil, "", err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.apiKeySeq++
+	key.ID = r.apiKeySeq
+	key.CreatedAt = time.Now()
+	r.apiKeys[key.ID] = &memoryAPIKey{APIKey: *key, userID: userID, hash: hashAPIKey(raw)}
+	return key, raw, nil
+}
+
+func (r *MemoryRepo) ListAPIKeys(_ context.Context, userID int) ([]models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := []models.APIKey{}
+	for id := r.apiKeySeq; id > 0; id-- {
+		if k, ok := r.apiKeys[id]; ok && k.userID == userID {
+			keys = append(keys, k.APIKey)
+		}
+	}
+	return keys, nil
+}
+
+func (r *MemoryRepo) RevokeAPIKey(_ context.Context, userID, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k, ok := r.apiKeys[id]
+	if !ok || k.userID != userID || k.RevokedAt != nil {
+		return ErrNotFound
+	}
+	now := time.Now()
+	k.RevokedAt = &now
+	return nil
+}
+
+// LookupAPIKey leaves Plan empty: MemoryRepo keeps no users.
+func (r *MemoryRepo) LookupAPIKey(_ context.Context, raw string) (models.APIKeyOwner, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	hash := hashAPIKey(raw)
+	now := time.Now()
+	for _, k := range r.apiKeys {
+		if k.hash != hash || k.RevokedAt != nil || (k.ExpiresAt != nil && !k.ExpiresAt.After(now)) {
+			continue
+		}
+		k.LastUsedAt = &now
+		return models.APIKeyOwner{KeyID: k.ID, UserID: k.userID, Scopes: slices.Clone(k.Scopes)}, nil
+	}
+	return models.APIKeyOwner{}, ErrNotFound
+}
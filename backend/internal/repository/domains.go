@@ -0,0 +1,312 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/brij-812/HyperLinkOS/internal/models"
+)
+
+// ErrDomainTaken is returned when a hostname already belongs to someone:
+// by CreateDomain if the user already claims it or another user verified
+// it, and by MarkDomainVerified if another user verified it first.
+var ErrDomainTaken = fmt.Errorf("domain already registered: %w", ErrConflict)
+
+// DomainStore manages per-user custom domains. Several users may claim the
+// same hostname; it belongs to whoever verifies it first, so an unverified
+// claim can't squat a domain its owner doesn't control.
+type DomainStore interface {
+	CreateDomain(ctx context.Context, userID int, hostname, token string) (*models.Domain, error)
+	ListDomains(ctx context.Context, userID int) ([]models.Domain, error)
+	GetDomain(ctx context.Context, userID, id int) (*models.Domain, error)
+	MarkDomainVerified(ctx context.Context, userID, id int) error
+	// DeleteDomain removes a domain; its links fall back to the default domain.
+	DeleteDomain(ctx context.Context, userID, id int) error
+	// ResolveHost maps a verified hostname to its domain ID for redirects.
+	ResolveHost(ctx context.Context, hostname string) (int, error)
+	// GetVerifiedDomain returns userID's verified domain for hostname.
+	GetVerifiedDomain(ctx context.Context, userID int, hostname string) (*models.Domain, error)
+}
+
+const (
+	domainHostCacheTTL    = 10 * time.Minute
+	domainHostNegativeTTL = time.Minute
+)
+
+func domainHostKey(hostname string) string {
+	return "domainhost:" + hostname
+}
+
+// ---------------------------------------------------------------------------
+// Postgres
+// ---------------------------------------------------------------------------
+
+func (r *PostgresRepo) CreateDomain(ctx context.Context, userID int, hostname, token string) (*models.Domain, error) {
+	d := &models.Domain{UserID: userID, Hostname: hostname, VerificationToken: token}
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO domains (user_id, hostname, verification_token)
+		SELECT $1, $2, $3
+		WHERE NOT EXISTS (SELECT 1 FROM domains WHERE hostname = $2 AND verified_at IS NOT NULL)
+		RETURNING id, created_at
+	`, userID, hostname, token).Scan(&d.ID, &d.CreatedAt)
+	// no row: verified by someone else; conflict: already claimed by this user
+	if err = dbError(ctx, "CreateDomain", err); errors.Is(err, ErrNotFound) || errors.Is(err, ErrConflict) {
+		return nil, ErrDomainTaken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *PostgresRepo) ListDomains(ctx context.Context, userID int) ([]models.Domain, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, hostname, verification_token, verified_at, created_at
+		FROM domains
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, dbError(ctx, "ListDomains", err)
+	}
+	defer rows.Close()
+
+	domains := []models.Domain{}
+	for rows.Next() {
+		d := models.Domain{UserID: userID}
+		var verifiedAt sql.NullTime
+		if err := rows.Scan(&d.ID, &d.Hostname, &d.VerificationToken, &verifiedAt, &d.CreatedAt); err != nil {
+			slog.ErrorContext(ctx, "❌ Scan domain error", "err", err)
+			continue
+		}
+		d.VerifiedAt = nullTimePtr(verifiedAt)
+		domains = append(domains, d)
+	}
+	return domains, dbError(ctx, "ListDomains", rows.Err())
+}
+
+func (r *PostgresRepo) GetDomain(ctx context.Context, userID, id int) (*models.Domain, error) {
+	return r.scanDomain(ctx, `
+		SELECT id, user_id, hostname, verification_token, verified_at, created_at
+		FROM domains
+		WHERE id = $1 AND user_id = $2
+	`, id, userID)
+}
+
+func (r *PostgresRepo) GetVerifiedDomain(ctx context.Context, userID int, hostname string) (*models.Domain, error) {
+	return r.scanDomain(ctx, `
+		SELECT id, user_id, hostname, verification_token, verified_at, created_at
+		FROM domains
+		WHERE hostname = $1 AND user_id = $2 AND verified_at IS NOT NULL
+	`, hostname, userID)
+}
+
+func (r *PostgresRepo) scanDomain(ctx context.Context, query string, args ...any) (*models.Domain, error) {
+	var d models.Domain
+	var verifiedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, args...).
+		Scan(&d.ID, &d.UserID, &d.Hostname, &d.VerificationToken, &verifiedAt, &d.CreatedAt)
+	if err != nil {
+		return nil, dbError(ctx, "Domain lookup", err)
+	}
+	d.VerifiedAt = nullTimePtr(verifiedAt)
+	return &d, nil
+}
+
+func (r *PostgresRepo) MarkDomainVerified(ctx context.Context, userID, id int) error {
+	var hostname string
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE domains SET verified_at = COALESCE(verified_at, NOW())
+		WHERE id = $1 AND user_id = $2
+		RETURNING hostname
+	`, id, userID).Scan(&hostname)
+	// idx_domains_verified_hostname: another user verified the hostname first
+	if err = dbError(ctx, "MarkDomainVerified", err); errors.Is(err, ErrConflict) {
+		return ErrDomainTaken
+	}
+	if err != nil {
+		return err
+	}
+	// drop a cached negative lookup so the host starts resolving right away
+	r.invalidate(ctx, domainHostKey(hostname))
+	return nil
+}
+
+func (r *PostgresRepo) DeleteDomain(ctx context.Context, userID, id int) error {
+	// codes on this domain are cached with its ID; collect them before the FK nulls it
+	rows, err := r.db.QueryContext(ctx, `SELECT code FROM links WHERE domain_id = $1`, id)
+	if err != nil {
+		return dbError(ctx, "DeleteDomain select", err)
+	}
+	var codes []string
+	for rows.Next() {
+		var code string
+		if rows.Scan(&code) == nil {
+			codes = append(codes, code)
+		}
+	}
+	rows.Close()
+
+	var hostname string
+	err = r.db.QueryRowContext(ctx, `
+		DELETE FROM domains WHERE id = $1 AND user_id = $2
+		RETURNING hostname
+	`, id, userID).Scan(&hostname)
+	if err != nil {
+		return dbError(ctx, "DeleteDomain", err)
+	}
+
+	keys := []string{domainHostKey(hostname)}
+	for _, code := range codes {
+		keys = append(keys, "shorturl:"+code)
+	}
+	r.invalidate(ctx, keys...)
+	return nil
+}
+
+// ResolveHost looks up a verified hostname, caching both hits and misses in Redis.
+func (r *PostgresRepo) ResolveHost(ctx context.Context, hostname string) (int, error) {
+	key := domainHostKey(hostname)
+	if cached, ok := r.cache.Get(ctx, key); ok {
+		if id, err := strconv.Atoi(cached); err == nil {
+			if id == 0 {
+				return 0, ErrNotFound
+			}
+			return id, nil
+		}
+	}
+
+	var id int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id FROM domains
+		WHERE hostname = $1 AND verified_at IS NOT NULL
+	`, hostname).Scan(&id)
+	if err = dbError(ctx, "ResolveHost", err); errors.Is(err, ErrNotFound) {
+		r.cache.Set(ctx, key, "0", domainHostNegativeTTL)
+		return 0, err
+	}
+	if err != nil {
+		return 0, err
+	}
+	r.cache.Set(ctx, key, strconv.Itoa(id), domainHostCacheTTL)
+	return id, nil
+}
+
+// ---------------------------------------------------------------------------
+// Memory
+// ---------------------------------------------------------------------------
+
+func (r *MemoryRepo) CreateDomain(_ context.Context, userID int, hostname, token string) (*models.Domain, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, d := range r.domains {
+		if d.Hostname == hostname && (d.UserID == userID || d.Verified()) {
+			return nil, ErrDomainTaken
+		}
+	}
+	r.domainSeq++
+	d := &models.Domain{
+		ID:                r.domainSeq,
+		UserID:            userID,
+		Hostname:          hostname,
+		VerificationToken: token,
+		CreatedAt:         time.Now(),
+	}
+	r.domains[d.ID] = d
+	out := *d
+	return &out, nil
+}
+
+func (r *MemoryRepo) ListDomains(_ context.Context, userID int) ([]models.Domain, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	domains := []models.Domain{}
+	for id := r.domainSeq; id > 0; id-- {
+		if d, ok := r.domains[id]; ok && d.UserID == userID {
+			domains = append(domains, *d)
+		}
+	}
+	return domains, nil
+}
+
+func (r *MemoryRepo) GetDomain(_ context.Context, userID, id int) (*models.Domain, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	d, ok := r.domains[id]
+	if !ok || d.UserID != userID {
+		return nil, ErrNotFound
+	}
+	out := *d
+	return &out, nil
+}
+
+func (r *MemoryRepo) GetVerifiedDomain(_ context.Context, userID int, hostname string) (*models.Domain, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, d := range r.domains {
+		if d.Hostname == hostname && d.UserID == userID && d.Verified() {
+			out := *d
+			return &out, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryRepo) MarkDomainVerified(_ context.Context, userID, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d, ok := r.domains[id]
+	if !ok || d.UserID != userID {
+		return ErrNotFound
+	}
+	for _, other := range r.domains {
+		if other.ID != id && other.Hostname == d.Hostname && other.Verified() {
+			return ErrDomainTaken
+		}
+	}
+	if d.VerifiedAt == nil {
+		now := time.Now()
+		d.VerifiedAt = &now
+	}
+	return nil
+}
+
+func (r *MemoryRepo) DeleteDomain(_ context.Context, userID, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d, ok := r.domains[id]
+	if !ok || d.UserID != userID {
+		return ErrNotFound
+	}
+	delete(r.domains, id)
+	// mirror ON DELETE SET NULL
+	for _, l := range r.links {
+		if l.DomainID == id {
+			l.DomainID = 0
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepo) ResolveHost(_ context.Context, hostname string) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for id, d := range r.domains {
+		if d.Hostname == hostname && d.Verified() {
+			return id, nil
+		}
+	}
+	return 0, ErrNotFound
+}
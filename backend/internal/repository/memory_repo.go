@@ -1,81 +1,178 @@
 package repository
 
 import (
-	"strings"
+	"context"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/brij-812/HyperLinkOS/internal/models"
 )
 
 type MemoryRepo struct {
 	mu           sync.RWMutex
-	urlToCode    map[string]string
-	codeToURL    map[string]string
+	links        map[string]*models.Link // code -> link
+	userLinks    map[int][]string        // user -> codes, in creation order
 	domainCounts map[int]map[string]int
-	userLinks    map[int][]map[string]string
+	clicks       []models.ClickEvent
+	domains      map[int]*models.Domain
+	seq          int64
+	domainSeq    int
+
+	refreshTokens map[int64]*models.RefreshToken
+	refreshSeq    int64
+	apiKeys       map[int]*memoryAPIKey
+	apiKeySeq     int
 }
 
 func NewMemoryRepo() *MemoryRepo {
 	return &MemoryRepo{
-		urlToCode:    make(map[string]string),
-		codeToURL:    make(map[string]string),
+		links:        make(map[string]*models.Link),
+		userLinks:    make(map[int][]string),
 		domainCounts: make(map[int]map[string]int),
-		userLinks:    make(map[int][]map[string]string),
+		domains:      make(map[int]*models.Domain),
+
+		refreshTokens: make(map[int64]*models.RefreshToken),
+		apiKeys:       make(map[int]*memoryAPIKey),
 	}
 }
 
-// Save a URL–code pair associated with a user
-func (r *MemoryRepo) Save(u, code string, userID int, expiresAt *time.Time) {
+// Save stores a link for its owner.
+// Returns ErrCodeTaken if the code is already mapped to a link.
+func (r *MemoryRepo) Save(_ context.Context, link *models.Link) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	r.urlToCode[u] = code
-	r.codeToURL[code] = u
+	if _, taken := r.links[link.Code]; taken {
+		return ErrCodeTaken
+	}
 
-	if _, ok := r.domainCounts[userID]; !ok {
-		r.domainCounts[userID] = make(map[string]int)
+	stored := *link
+	if link.ClicksLeft != nil {
+		n := *link.ClicksLeft
+		stored.ClicksLeft = &n
 	}
+	if stored.CreatedAt.IsZero() {
+		stored.CreatedAt = time.Now()
+	}
+	r.links[link.Code] = &stored
+	r.userLinks[link.UserID] = append(r.userLinks[link.UserID], link.Code)
 
-	// Track per-user links
-	r.userLinks[userID] = append(r.userLinks[userID], map[string]string{
-		"short_url":  "http://localhost:8080/" + code,
-		"long_url":   u,
-		"created_at": time.Now().Format(time.RFC3339),
-	})
+	if _, ok := r.domainCounts[link.UserID]; !ok {
+		r.domainCounts[link.UserID] = make(map[string]int)
+	}
 
 	// Increment domain count per user
-	domain := extractDomain(u)
+	domain := extractDomain(link.LongURL)
 	if domain != "" {
-		r.domainCounts[userID][domain]++
+		r.domainCounts[link.UserID][domain]++
 	}
+	return nil
 }
 
-func (r *MemoryRepo) GetCode(u string) (string, bool) {
+// NextID returns the next value of an in-process counter (sequence code strategy).
+func (r *MemoryRepo) NextID() (int64, error) {
+	return atomic.AddInt64(&r.seq, 1), nil
+}
+
+func (r *MemoryRepo) GetCode(_ context.Context, u string, userID, domainID int) (string, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	code, ok := r.urlToCode[u]
-	return code, ok
+
+	now := time.Now()
+	for _, code := range r.userLinks[userID] {
+		if l := r.links[code]; l.LongURL == u && l.DomainID == domainID && !l.Protected() && l.ClicksLeft == nil && !l.Pending(now) && !l.Expired(now) {
+			return code, nil
+		}
+	}
+	return "", ErrNotFound
 }
 
-func (r *MemoryRepo) GetURL(code string) (string, bool) {
+func (r *MemoryRepo) GetSharedCode(_ context.Context, u string) (string, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	u, ok := r.codeToURL[code]
-	return u, ok
+
+	now := time.Now()
+	for code, l := range r.links {
+		if l.Shared && l.DomainID == 0 && l.LongURL == u && !l.Protected() && l.ClicksLeft == nil && !l.Pending(now) && !l.Expired(now) {
+			return code, nil
+		}
+	}
+	return "", ErrNotFound
+}
+
+func (r *MemoryRepo) GetURL(_ context.Context, code string, domainID int) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l, ok := r.links[code]
+	if !ok || l.DomainID != domainID {
+		return "", ErrNotFound
+	}
+	if l.Expired(time.Now()) {
+		return "", ErrExpired
+	}
+	if l.Exhausted() {
+		return "", ErrExhausted
+	}
+	if l.Pending(time.Now()) {
+		return "", ErrNotActive
+	}
+	if l.Protected() {
+		return "", ErrPasswordRequired
+	}
+	if l.ClicksLeft != nil {
+		*l.ClicksLeft--
+	}
+	return l.LongURL, nil
+}
+
+func (r *MemoryRepo) GetProtectedURL(_ context.Context, code string, domainID int) (string, string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	l, ok := r.links[code]
+	if !ok || l.DomainID != domainID || !l.Protected() {
+		return "", "", ErrNotFound
+	}
+	if l.Expired(time.Now()) {
+		return "", "", ErrExpired
+	}
+	if l.Exhausted() {
+		return "", "", ErrExhausted
+	}
+	if l.Pending(time.Now()) {
+		return "", "", ErrNotActive
+	}
+	return l.LongURL, l.PasswordHash, nil
+}
+
+func (r *MemoryRepo) ConsumeClick(_ context.Context, code string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l, ok := r.links[code]
+	if !ok || l.ClicksLeft == nil {
+		return nil
+	}
+	if *l.ClicksLeft <= 0 {
+		return ErrExhausted
+	}
+	*l.ClicksLeft--
+	return nil
 }
 
 // GetTopDomains — per-user
-func (r *MemoryRepo) GetTopDomains(userID, n int) map[string]int {
+func (r *MemoryRepo) GetTopDomains(_ context.Context, userID, n int) (map[string]int, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	if domains, ok := r.domainCounts[userID]; ok {
-		return domains
+	out := make(map[string]int, len(r.domainCounts[userID]))
+	for domain, count := range r.domainCounts[userID] {
+		out[domain] = count
 	}
-	return map[string]int{}
+	return out, nil
 }
 
 // IncrementDomainCount — per-user
-func (r *MemoryRepo) IncrementDomainCount(u string, userID int) {
+func (r *MemoryRepo) IncrementDomainCount(_ context.Context, u string, userID int) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -87,41 +184,58 @@ func (r *MemoryRepo) IncrementDomainCount(u string, userID int) {
 	if domain != "" {
 		r.domainCounts[userID][domain]++
 	}
+	return nil
 }
 
-// GetAllURLsByUser — unchanged
-func (r *MemoryRepo) GetAllURLsByUser(userID int) []map[string]string {
+// GetAllURLsByUser returns the user's links in creation order.
+func (r *MemoryRepo) GetAllURLsByUser(_ context.Context, userID int) ([]models.Link, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	return r.userLinks[userID]
+
+	codes := r.userLinks[userID]
+	results := make([]models.Link, 0, len(codes))
+	for _, code := range codes {
+		l := *r.links[code]
+		if d, ok := r.domains[l.DomainID]; ok {
+			l.Domain = d.Hostname
+		}
+		results = append(results, l)
+	}
+	return results, nil
 }
 
-func (r *MemoryRepo) DeleteLink(userID int, code string) bool {
+// DeleteLink removes a link only if it belongs to userID.
+func (r *MemoryRepo) DeleteLink(_ context.Context, userID int, code string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	// find the long URL associated with the code
-	u, ok := r.codeToURL[code]
-	if !ok {
-		return false
+	l, ok := r.links[code]
+	if !ok || l.UserID != userID {
+		return ErrNotFound
 	}
+	delete(r.links, code)
 
-	// remove from main maps
-	delete(r.codeToURL, code)
-	delete(r.urlToCode, u)
+	// drop the link's click history so a reused code starts clean
+	kept := r.clicks[:0]
+	for _, ev := range r.clicks {
+		if ev.Code != code {
+			kept = append(kept, ev)
+		}
+	}
+	r.clicks = kept
 
-	// remove from user's links slice
-	links := r.userLinks[userID]
-	newLinks := make([]map[string]string, 0, len(links))
-	for _, link := range links {
-		if !strings.HasSuffix(link["short_url"], "/"+code) {
-			newLinks = append(newLinks, link)
+	// remove from user's code list
+	codes := r.userLinks[userID]
+	keptCodes := make([]string, 0, len(codes))
+	for _, c := range codes {
+		if c != code {
+			keptCodes = append(keptCodes, c)
 		}
 	}
-	r.userLinks[userID] = newLinks
+	r.userLinks[userID] = keptCodes
 
 	// decrement domain count if applicable
-	domain := extractDomain(u)
+	domain := extractDomain(l.LongURL)
 	if domain != "" && r.domainCounts[userID][domain] > 0 {
 		r.domainCounts[userID][domain]--
 		if r.domainCounts[userID][domain] == 0 {
@@ -129,5 +243,105 @@ func (r *MemoryRepo) DeleteLink(userID int, code string) bool {
 		}
 	}
 
-	return true
+	return nil
+}
+
+// UpdateLink edits a link owned by userID, moving its domain count if the destination host changed.
+func (r *MemoryRepo) UpdateLink(_ context.Context, userID int, code string, upd models.LinkUpdate) (*models.Link, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l, ok := r.links[code]
+	if !ok || l.UserID != userID {
+		return nil, ErrNotFound
+	}
+	if upd.SetExpiry && expiresBeforeActivation(upd.ExpiresAt, l.ActivatesAt) {
+		return nil, ErrExpiryBeforeActivation
+	}
+
+	if upd.LongURL != nil && *upd.LongURL != l.LongURL {
+		oldDomain, newDomain := extractDomain(l.LongURL), extractDomain(*upd.LongURL)
+		if oldDomain != newDomain {
+			if oldDomain != "" && r.domainCounts[userID][oldDomain] > 0 {
+				r.domainCounts[userID][oldDomain]--
+				if r.domainCounts[userID][oldDomain] == 0 {
+					delete(r.domainCounts[userID], oldDomain)
+				}
+			}
+			if newDomain != "" {
+				if _, ok := r.domainCounts[userID]; !ok {
+					r.domainCounts[userID] = make(map[string]int)
+				}
+				r.domainCounts[userID][newDomain]++
+			}
+		}
+		l.LongURL = *upd.LongURL
+	}
+	if upd.Shared != nil {
+		l.Shared = *upd.Shared
+	}
+	if upd.SetExpiry {
+		l.ExpiresAt = upd.ExpiresAt
+	}
+
+	out := *l
+	if d, ok := r.domains[out.DomainID]; ok {
+		out.Domain = d.Hostname
+	}
+	return &out, nil
+}
+
+// SaveClicks appends click events to the in-memory log.
+func (r *MemoryRepo) SaveClicks(_ context.Context, events []models.ClickEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clicks = append(r.clicks, events...)
+	return nil
+}
+
+// GetLinkStats aggregates the in-memory click log for a link owned by userID.
+func (r *MemoryRepo) GetLinkStats(_ context.Context, userID int, code string, since time.Time, bucket string) (*models.LinkStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	l, ok := r.links[code]
+	if !ok || l.UserID != userID {
+		return nil, ErrNotFound
+	}
+
+	stats := &models.LinkStats{Code: code, Bucket: bucket, Since: since}
+	dayVisitors := make(map[time.Time]map[string]struct{})
+	points := make(map[time.Time]models.StatsBucket)
+	bucketVisitors := make(map[time.Time]map[string]struct{})
+
+	for _, ev := range r.clicks {
+		if ev.Code != code {
+			continue
+		}
+		stats.TotalClicks++
+		day := truncateToBucket(ev.ClickedAt, BucketDay)
+		if dayVisitors[day] == nil {
+			dayVisitors[day] = make(map[string]struct{})
+		}
+		dayVisitors[day][ev.IPHash] = struct{}{}
+
+		if ev.ClickedAt.Before(since) {
+			continue
+		}
+		start := truncateToBucket(ev.ClickedAt, bucket)
+		p := points[start]
+		p.Start = start
+		p.Clicks++
+		if bucketVisitors[start] == nil {
+			bucketVisitors[start] = make(map[string]struct{})
+		}
+		bucketVisitors[start][ev.IPHash] = struct{}{}
+		p.Unique = len(bucketVisitors[start])
+		points[start] = p
+	}
+	for _, v := range dayVisitors {
+		stats.UniqueVisitors += len(v)
+	}
+	stats.Series = fillSeries(points, since, time.Now(), bucket)
+	return stats, nil
 }
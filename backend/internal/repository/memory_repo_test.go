@@ -1,49 +1,97 @@
 package repository
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/brij-812/HyperLinkOS/internal/models"
 )
 
 func TestSaveAndGet(t *testing.T) {
 	r := NewMemoryRepo()
+	ctx := context.Background()
 	userID := 1
 
 	// save one URL (no expiry)
-	r.Save("https://a.com", "abc", userID, nil)
+	r.Save(ctx, &models.Link{LongURL: "https://a.com", Code: "abc", UserID: userID})
 
 	// verify GetCode
-	if c, ok := r.GetCode("https://a.com"); !ok || c != "abc" {
+	if c, err := r.GetCode(ctx, "https://a.com", userID, 0); err != nil || c != "abc" {
 		t.Fatalf("expected code abc, got %s", c)
 	}
 
 	// verify GetURL
-	if u, ok := r.GetURL("abc"); !ok || u != "https://a.com" {
+	if u, err := r.GetURL(ctx, "abc", 0); err != nil || u != "https://a.com" {
 		t.Fatalf("expected url https://a.com, got %s", u)
 	}
 
+	// saving another link under the same code must fail
+	if err := r.Save(ctx, &models.Link{LongURL: "https://b.com", Code: "abc", UserID: userID}); !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected ErrCodeTaken, got %v", err)
+	}
+
 	// verify GetAllURLsByUser
-	urls := r.GetAllURLsByUser(userID)
+	urls, _ := r.GetAllURLsByUser(ctx, userID)
 	if len(urls) != 1 {
 		t.Fatalf("expected 1 url for user, got %d", len(urls))
 	}
-	if urls[0]["long_url"] != "https://a.com" {
+	if urls[0].LongURL != "https://a.com" {
 		t.Fatalf("expected long_url=https://a.com, got %v", urls[0])
 	}
 }
 
+func TestLinksAreOwnedPerUser(t *testing.T) {
+	r := NewMemoryRepo()
+	ctx := context.Background()
+
+	r.Save(ctx, &models.Link{LongURL: "https://a.com", Code: "userA1", UserID: 1})
+	r.Save(ctx, &models.Link{LongURL: "https://a.com", Code: "userB1", UserID: 2, Shared: true})
+
+	if c, err := r.GetCode(ctx, "https://a.com", 1, 0); err != nil || c != "userA1" {
+		t.Fatalf("expected user 1 to own userA1, got %q", c)
+	}
+	if c, err := r.GetCode(ctx, "https://a.com", 2, 0); err != nil || c != "userB1" {
+		t.Fatalf("expected user 2 to own userB1, got %q", c)
+	}
+	if _, err := r.GetCode(ctx, "https://a.com", 3, 0); err != ErrNotFound {
+		t.Fatal("expected user 3 to have no link for https://a.com")
+	}
+	if c, err := r.GetSharedCode(ctx, "https://a.com"); err != nil || c != "userB1" {
+		t.Fatalf("expected shared code userB1, got %q", c)
+	}
+
+	// a user cannot delete someone else's link
+	if err := r.DeleteLink(ctx, 2, "userA1"); err != ErrNotFound {
+		t.Fatal("expected delete of another user's link to fail")
+	}
+	if err := r.DeleteLink(ctx, 1, "userA1"); err != nil {
+		t.Fatal("expected owner delete to succeed")
+	}
+	if urls, _ := r.GetAllURLsByUser(ctx, 2); len(urls) != 1 {
+		t.Fatal("expected user 2's link to be untouched")
+	}
+}
+
 func TestDomainCount(t *testing.T) {
 	r := NewMemoryRepo()
+	ctx := context.Background()
 	userID := 42
 
 	for i := 0; i < 3; i++ {
-		r.Save("https://a.com", "a", userID, nil)
+		r.Save(ctx, &models.Link{LongURL: "https://a.com", Code: fmt.Sprintf("a%d", i), UserID: userID})
 	}
 	for i := 0; i < 2; i++ {
-		r.Save("https://b.com", "b", userID, nil)
+		r.Save(ctx, &models.Link{LongURL: "https://b.com", Code: fmt.Sprintf("b%d", i), UserID: userID})
 	}
 
-	top := r.GetTopDomains(userID, 3)
+	top, _ := r.GetTopDomains(ctx, userID, 3)
 	if top["a.com"] != 3 || top["b.com"] != 2 {
 		t.Fatalf("unexpected domain counts %v", top)
 	}
@@ -52,21 +100,220 @@ func TestDomainCount(t *testing.T) {
 // ✅ Bonus test for expiry behavior (optional)
 func TestSaveWithExpiry(t *testing.T) {
 	r := NewMemoryRepo()
+	ctx := context.Background()
 	userID := 99
 
 	exp := time.Now().Add(2 * time.Hour)
-	r.Save("https://temp.com", "t123", userID, &exp)
+	r.Save(ctx, &models.Link{LongURL: "https://temp.com", Code: "t123", UserID: userID, ExpiresAt: &exp})
 
-	u, ok := r.GetURL("t123")
-	if !ok || u != "https://temp.com" {
+	u, err := r.GetURL(ctx, "t123", 0)
+	if err != nil || u != "https://temp.com" {
 		t.Fatalf("expected active link https://temp.com, got %s", u)
 	}
 
 	// simulate expired link
 	past := time.Now().Add(-2 * time.Hour)
-	r.Save("https://expired.com", "e123", userID, &past)
+	r.Save(ctx, &models.Link{LongURL: "https://expired.com", Code: "e123", UserID: userID, ExpiresAt: &past})
+
+	if _, err := r.GetURL(ctx, "e123", 0); err != ErrExpired {
+		t.Fatalf("expected ErrExpired for expired link, got %v", err)
+	}
+	if _, err := r.GetURL(ctx, "nope", 0); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound for unknown code, got %v", err)
+	}
+}
+
+func TestClickLimitedLink(t *testing.T) {
+	r := NewMemoryRepo()
+	ctx := context.Background()
+
+	limit := 5
+	r.Save(ctx, &models.Link{LongURL: "https://dl.com/file", Code: "dl1", UserID: 1, ClicksLeft: &limit})
+	if _, err := r.GetCode(ctx, "https://dl.com/file", 1, 0); err != ErrNotFound {
+		t.Fatal("click-limited links must not be reused")
+	}
+
+	// concurrent redirects never spend more clicks than the link has
+	var wg sync.WaitGroup
+	var served atomic.Int32
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := r.GetURL(ctx, "dl1", 0); err == nil {
+				served.Add(1)
+			} else if err != ErrExhausted {
+				t.Errorf("expected ErrExhausted, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if served.Load() != 5 {
+		t.Fatalf("expected exactly 5 redirects, got %d", served.Load())
+	}
+	if err := r.ConsumeClick(ctx, "dl1"); err != ErrExhausted {
+		t.Fatalf("expected ErrExhausted from ConsumeClick, got %v", err)
+	}
+	if limit != 5 {
+		t.Fatal("Save must not alias the caller's counter")
+	}
+}
+
+func TestMemoryDomainClaims(t *testing.T) {
+	checkDomainClaims(t, NewMemoryRepo())
+}
+
+// checkDomainClaims runs against every DomainStore; it needs users 1 to 3.
+func checkDomainClaims(t *testing.T, s DomainStore) {
+	t.Helper()
+	ctx := context.Background()
+
+	// an unverified claim doesn't stop the real owner from claiming and verifying
+	squat, err := s.CreateDomain(ctx, 1, "go.example.com", "t1")
+	if err != nil {
+		t.Fatalf("first claim: %v", err)
+	}
+	owner, err := s.CreateDomain(ctx, 2, "go.example.com", "t2")
+	if err != nil {
+		t.Fatalf("expected a second user to claim an unverified hostname, got %v", err)
+	}
+	if _, err := s.CreateDomain(ctx, 2, "go.example.com", "t3"); !errors.Is(err, ErrDomainTaken) {
+		t.Fatalf("expected ErrDomainTaken for a duplicate claim, got %v", err)
+	}
+	if err := s.MarkDomainVerified(ctx, 2, owner.ID); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	// once verified, the hostname is taken for everyone else
+	if err := s.MarkDomainVerified(ctx, 1, squat.ID); !errors.Is(err, ErrDomainTaken) {
+		t.Fatalf("expected ErrDomainTaken verifying a hostname someone else verified, got %v", err)
+	}
+	if _, err := s.CreateDomain(ctx, 3, "go.example.com", "t4"); !errors.Is(err, ErrDomainTaken) {
+		t.Fatalf("expected ErrDomainTaken claiming a verified hostname, got %v", err)
+	}
+	if err := s.MarkDomainVerified(ctx, 2, owner.ID); err != nil {
+		t.Fatalf("expected verifying twice to be a no-op, got %v", err)
+	}
 
-	if _, ok := r.GetURL("e123"); ok {
-		t.Fatalf("expected expired link to be inaccessible")
+	if id, err := s.ResolveHost(ctx, "go.example.com"); err != nil || id != owner.ID {
+		t.Fatalf("expected the host to resolve to domain %d, got %d (%v)", owner.ID, id, err)
+	}
+	if _, err := s.GetVerifiedDomain(ctx, 1, "go.example.com"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected the squatter to have no verified domain, got %v", err)
+	}
+	if d, err := s.GetDomain(ctx, 1, squat.ID); err != nil || d.Verified() {
+		t.Fatalf("expected the squatter's claim to stay unverified, got %+v (%v)", d, err)
+	}
+	if err := s.DeleteDomain(ctx, 1, squat.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if domains, _ := s.ListDomains(ctx, 1); len(domains) != 0 {
+		t.Fatalf("expected the squatter to have no domains left, got %v", domains)
+	}
+}
+
+func TestMemoryRefreshTokens(t *testing.T) {
+	checkRefreshTokens(t, NewMemoryRepo())
+}
+
+func TestMemoryAPIKeys(t *testing.T) {
+	checkAPIKeys(t, NewMemoryRepo())
+}
+
+// checkRefreshTokens runs against every RefreshTokenStore; it needs user 1.
+func checkRefreshTokens(t *testing.T, s RefreshTokenStore) {
+	t.Helper()
+	ctx := context.Background()
+	expires := time.Now().Add(time.Hour)
+
+	first := &models.RefreshToken{UserID: 1, FamilyID: "fam", TokenHash: "h1", ExpiresAt: expires}
+	if err := s.CreateRefreshToken(ctx, first); err != nil || first.ID == 0 {
+		t.Fatalf("create: id %d, %v", first.ID, err)
+	}
+	other := &models.RefreshToken{UserID: 1, FamilyID: "other", TokenHash: "o1", ExpiresAt: expires}
+	if err := s.CreateRefreshToken(ctx, other); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	got, err := s.GetRefreshToken(ctx, "h1")
+	if err != nil || got.ID != first.ID || got.UserID != 1 || got.FamilyID != "fam" || got.RevokedAt != nil {
+		t.Fatalf("unexpected token %+v (%v)", got, err)
+	}
+	if got.ExpiresAt.Sub(expires).Abs() > time.Millisecond {
+		t.Errorf("expected expiry %v, got %v", expires, got.ExpiresAt)
+	}
+	if _, err := s.GetRefreshToken(ctx, "nope"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for an unknown hash, got %v", err)
+	}
+
+	// rotating revokes the old token; a second rotation of it loses
+	second := &models.RefreshToken{UserID: 1, FamilyID: "fam", TokenHash: "h2", ExpiresAt: expires}
+	if err := s.RotateRefreshToken(ctx, first.ID, second); err != nil || second.ID == 0 {
+		t.Fatalf("rotate: id %d, %v", second.ID, err)
+	}
+	if got, _ := s.GetRefreshToken(ctx, "h1"); got == nil || got.RevokedAt == nil {
+		t.Fatalf("expected the rotated token to be revoked, got %+v", got)
+	}
+	third := &models.RefreshToken{UserID: 1, FamilyID: "fam", TokenHash: "h3", ExpiresAt: expires}
+	if err := s.RotateRefreshToken(ctx, first.ID, third); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("expected ErrTokenRevoked rotating a revoked token, got %v", err)
+	}
+	if _, err := s.GetRefreshToken(ctx, "h3"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected a failed rotation to store nothing, got %v", err)
+	}
+
+	if err := s.RevokeRefreshFamily(ctx, "fam"); err != nil {
+		t.Fatalf("revoke family: %v", err)
+	}
+	if got, _ := s.GetRefreshToken(ctx, "h2"); got == nil || got.RevokedAt == nil {
+		t.Fatalf("expected the family to be revoked, got %+v", got)
+	}
+	if got, _ := s.GetRefreshToken(ctx, "o1"); got == nil || got.RevokedAt != nil {
+		t.Fatalf("expected other families to stay live, got %+v", got)
+	}
+}
+
+// checkAPIKeys runs against every APIKeyStore; it needs users 1 and 2.
+func checkAPIKeys(t *testing.T, s APIKeyStore) {
+	t.Helper()
+	ctx := context.Background()
+
+	key, raw, err := s.CreateAPIKey(ctx, 1, "ci", []string{models.ScopeLinksRead}, nil)
+	if err != nil || key.ID == 0 || !strings.HasPrefix(raw, APIKeyPrefix) || !strings.HasPrefix(raw, key.Prefix) {
+		t.Fatalf("create: %+v %q (%v)", key, raw, err)
+	}
+	past := time.Now().Add(-time.Minute)
+	_, expiredRaw, err := s.CreateAPIKey(ctx, 1, "old", []string{models.ScopeLinksRead}, &past)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	owner, err := s.LookupAPIKey(ctx, raw)
+	if err != nil || owner.KeyID != key.ID || owner.UserID != 1 || !slices.Equal(owner.Scopes, []string{models.ScopeLinksRead}) {
+		t.Fatalf("unexpected owner %+v (%v)", owner, err)
+	}
+	if _, err := s.LookupAPIKey(ctx, expiredRaw); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for an expired key, got %v", err)
+	}
+	if _, err := s.LookupAPIKey(ctx, APIKeyPrefix+"unknown"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for an unknown key, got %v", err)
+	}
+
+	keys, err := s.ListAPIKeys(ctx, 1)
+	if err != nil || len(keys) != 2 || keys[1].ID != key.ID || keys[1].LastUsedAt == nil {
+		t.Fatalf("expected both keys, newest first, with last use recorded: %+v (%v)", keys, err)
+	}
+
+	if err := s.RevokeAPIKey(ctx, 2, key.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound revoking another user's key, got %v", err)
+	}
+	if err := s.RevokeAPIKey(ctx, 1, key.ID); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if err := s.RevokeAPIKey(ctx, 1, key.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound revoking twice, got %v", err)
+	}
+	if _, err := s.LookupAPIKey(ctx, raw); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a revoked key, got %v", err)
 	}
 }
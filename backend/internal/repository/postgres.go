@@ -4,23 +4,45 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/url"
 	"strings"
 	"time"
 
 	"github.com/brij-812/HyperLinkOS/internal/cache"
+	"github.com/brij-812/HyperLinkOS/internal/metrics"
+	"github.com/brij-812/HyperLinkOS/internal/models"
+	"github.com/lib/pq"
 )
 
 // PostgresRepo stores data in Postgres instead of memory.
 type PostgresRepo struct {
-	db *sql.DB
+	db    tracedDB
+	cache cache.Cache
+
+	// What to do on a cache miss while the cache is down: cache.FailOpen
+	// (default) reads through to Postgres, cache.FailClosed returns
+	// ErrUnavailable so an outage can't turn into a database stampede.
+	RedirectCachePolicy cache.Policy
+	MetricsCachePolicy  cache.Policy
 }
 
-// Constructor
-func NewPostgresRepo(db *sql.DB) *PostgresRepo {
-	return &PostgresRepo{db: db}
+// Constructor; a nil cache disables caching.
+func NewPostgresRepo(db *sql.DB, c cache.Cache) *PostgresRepo {
+	return &PostgresRepo{db: tracedDB{db}, cache: cache.OrNoop(c)}
+}
+
+// invalidate drops cache keys after a write. It ignores request
+// cancellation: once the database has changed, the cache must follow.
+func (r *PostgresRepo) invalidate(ctx context.Context, keys ...string) {
+	r.cache.Delete(context.WithoutCancel(ctx), keys...)
+}
+
+// cacheDown reports whether a cache miss must fail under policy.
+func (r *PostgresRepo) cacheDown(policy cache.Policy) bool {
+	return policy == cache.FailClosed && !cache.Available(r.cache)
 }
 
 // 🧩 Extracts clean domain (removes www.)
@@ -36,123 +58,328 @@ func extractDomain(rawURL string) string {
 	return host
 }
 
-// Save inserts a new URL–code pair associated with a user.
-// Supports optional expiry (TTL). If expiresAt is nil, link never expires.
-func (r *PostgresRepo) Save(u, code string, userID int, expiresAt *time.Time) {
-	_, err := r.db.ExecContext(context.Background(), `
-		INSERT INTO links (code, long_url, user_id, created_at, expires_at)
-		VALUES ($1, $2, $3, $4, $5)
+// Save inserts a new link owned by link.UserID.
+// Supports optional expiry (TTL). If ExpiresAt is nil, link never expires.
+// Returns ErrCodeTaken if the code already exists.
+func (r *PostgresRepo) Save(ctx context.Context, link *models.Link) error {
+	u, userID := link.LongURL, link.UserID
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO links (code, long_url, user_id, shared, domain_id, created_at, expires_at, password_hash, clicks_left, activates_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, 0), $6, $7, NULLIF($8, ''), $9, $10)
 		ON CONFLICT (code) DO NOTHING
-	`, code, u, userID, time.Now(), expiresAt)
+	`, link.Code, u, userID, link.Shared, link.DomainID, time.Now(), link.ExpiresAt, link.PasswordHash, link.ClicksLeft, link.ActivatesAt)
 	if err != nil {
-		log.Printf("❌ Failed to save URL: %v", err)
-		return
+		return dbError(ctx, "Save", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrCodeTaken
 	}
 
 	// Increment domain count (user-specific)
 	domain := extractDomain(u)
-	log.Printf("🧩 Extracted domain for %s = '%s'", u, domain)
+	slog.DebugContext(ctx, "🧩 Extracted domain", "url", u, "domain", domain)
 	if domain != "" {
-		log.Printf("🧠 DEBUG: Save() called for URL=%s userID=%d domain=%s", u, userID, domain)
-		_, err = r.db.ExecContext(context.Background(), `
+		slog.DebugContext(ctx, "🧠 Counting domain", "url", u, "user_id", userID, "domain", domain)
+		_, err = r.db.ExecContext(ctx, `
 			INSERT INTO domain_counts (domain, user_id, count)
 			VALUES ($1, $2, 1)
 			ON CONFLICT (domain, user_id)
 			DO UPDATE SET count = domain_counts.count + 1
 		`, domain, userID)
 		if err != nil {
-			log.Printf("❌ INSERT domain_counts failed: %v", err)
+			// the link itself is stored; a missed metrics bump is not worth failing the request
+			slog.ErrorContext(ctx, "❌ INSERT domain_counts failed", "err", err)
 		} else {
-			log.Printf("✅ INSERT domain_counts succeeded for domain=%s userID=%d", domain, userID)
+			slog.DebugContext(ctx, "✅ INSERT domain_counts succeeded", "domain", domain, "user_id", userID)
 		}
 	}
 
-	// 🧹 Invalidate cached metrics for this user
-	cache.Delete(fmt.Sprintf("metrics:topdomains:%d", userID))
+	// 🧹 Invalidate cached metrics for this user, and a cached miss for the code
+	r.invalidate(ctx, "shorturl:"+link.Code, fmt.Sprintf("metrics:topdomains:%d", userID))
+	return nil
 }
 
-// GetCode finds the short code for a given long URL
-func (r *PostgresRepo) GetCode(u string) (string, bool) {
+// NextID returns the next value of link_code_seq (used by the sequence code strategy).
+func (r *PostgresRepo) NextID() (int64, error) {
+	var id int64
+	if err := r.db.QueryRow(`SELECT nextval('link_code_seq')`).Scan(&id); err != nil {
+		return 0, dbError(context.Background(), "NextID", err)
+	}
+	return id, nil
+}
+
+// GetCode finds the user's own live short code for a long URL on a domain (0 = default)
+func (r *PostgresRepo) GetCode(ctx context.Context, u string, userID, domainID int) (string, error) {
 	var code string
-	err := r.db.QueryRow(`SELECT code FROM links WHERE long_url=$1`, u).Scan(&code)
-	if err == sql.ErrNoRows {
-		return "", false
+	err := r.db.QueryRowContext(ctx, `
+		SELECT code FROM links
+		WHERE long_url = $1 AND user_id = $2
+		  AND COALESCE(domain_id, 0) = $3
+		  AND password_hash IS NULL AND clicks_left IS NULL
+		  AND (activates_at IS NULL OR activates_at <= NOW())
+		  AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, u, userID, domainID).Scan(&code)
+	if err != nil {
+		return "", dbError(ctx, "GetCode", err)
 	}
+	return code, nil
+}
+
+// GetSharedCode finds a live default-domain code for a long URL whose owner opted in to sharing
+func (r *PostgresRepo) GetSharedCode(ctx context.Context, u string) (string, error) {
+	var code string
+	err := r.db.QueryRowContext(ctx, `
+		SELECT code FROM links
+		WHERE long_url = $1 AND shared AND domain_id IS NULL
+		  AND password_hash IS NULL AND clicks_left IS NULL
+		  AND (activates_at IS NULL OR activates_at <= NOW())
+		  AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY created_at
+		LIMIT 1
+	`, u).Scan(&code)
 	if err != nil {
-		log.Printf("❌ GetCode error: %v", err)
-		return "", false
+		return "", dbError(ctx, "GetSharedCode", err)
 	}
-	return code, true
+	return code, nil
 }
 
-// GetURL finds the original long URL for a given code (public).
-// Automatically skips expired links.
-func (r *PostgresRepo) GetURL(code string) (string, bool) {
+// cachedLink is the value stored under shorturl:{code}. The domain is kept
+// alongside the URL so cache hits can still be scoped by Host. Codes that
+// don't exist, or have expired, are cached too (Missing / Expired) so
+// scanners probing random codes don't reach Postgres on every request.
+// Password-protected links are cached as Protected, without their URL.
+// Click-limited links are cached as Limited: every hit still spends a click
+// in Postgres, and once none are left the entry becomes Exhausted. Scheduled
+// links are cached as Pending until they activate, and live entries never
+// outlast the link's expiry.
+type cachedLink struct {
+	URL       string `json:"u,omitempty"`
+	DomainID  int    `json:"d,omitempty"`
+	Missing   bool   `json:"m,omitempty"`
+	Expired   bool   `json:"x,omitempty"`
+	Protected bool   `json:"p,omitempty"`
+	Limited   bool   `json:"l,omitempty"`
+	Exhausted bool   `json:"e,omitempty"`
+	Pending   bool   `json:"a,omitempty"`
+}
+
+// negativeCacheTTL bounds how long a miss is remembered. Save and
+// UpdateLink invalidate the key, so it rarely has to run out.
+// linkCacheTTL bounds how long a live link is cached.
+const (
+	negativeCacheTTL = 5 * time.Minute
+	linkCacheTTL     = 24 * time.Hour
+)
+
+// GetURL finds the original long URL for a code served on domainID (public).
+// 0 is the default public domain. Returns ErrExpired for links past their expiry.
+// Resolving a click-limited link spends one of its clicks.
+func (r *PostgresRepo) GetURL(ctx context.Context, code string, domainID int) (string, error) {
 	cacheKey := "shorturl:" + code
 
 	// 1️⃣ check Redis cache first
-	if cached, ok := cache.Get(cacheKey); ok {
-		return cached, true
+	if raw, ok := r.cache.Get(ctx, cacheKey); ok {
+		var c cachedLink
+		if err := json.Unmarshal([]byte(raw), &c); err == nil {
+			metrics.RedirectCacheLookup(true)
+			switch {
+			case c.Missing, c.DomainID != domainID:
+				return "", ErrNotFound
+			case c.Expired:
+				return "", ErrExpired
+			case c.Exhausted:
+				return "", ErrExhausted
+			case c.Pending:
+				return "", ErrNotActive
+			case c.Protected:
+				return "", ErrPasswordRequired
+			case c.Limited:
+				if err := r.spendClick(ctx, cacheKey, code); err != nil {
+					return "", err
+				}
+			}
+			return c.URL, nil
+		}
+	}
+
+	metrics.RedirectCacheLookup(false)
+
+	if r.cacheDown(r.RedirectCachePolicy) {
+		return "", fmt.Errorf("GetURL: %w: cache down", ErrUnavailable)
 	}
 
 	// 2️⃣ fallback to Postgres
 	var u string
-	var expiresAt sql.NullTime
-	err := r.db.QueryRow(`
-		SELECT long_url, expires_at
+	var linkDomain int
+	var expiresAt, activatesAt sql.NullTime
+	var protected bool
+	var clicksLeft sql.NullInt64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT long_url, COALESCE(domain_id, 0), expires_at, activates_at, password_hash IS NOT NULL, clicks_left
 		FROM links
 		WHERE code = $1
-	`, code).Scan(&u, &expiresAt)
-
+	`, code).Scan(&u, &linkDomain, &expiresAt, &activatesAt, &protected, &clicksLeft)
 	if err == sql.ErrNoRows {
-		return "", false
+		r.cacheLink(ctx, cacheKey, cachedLink{Missing: true}, negativeCacheTTL)
+		return "", ErrNotFound
 	}
 	if err != nil {
-		log.Printf("❌ GetURL error: %v", err)
-		return "", false
+		return "", dbError(ctx, "GetURL", err)
 	}
 
 	// 🕓 Check expiry
 	if expiresAt.Valid && time.Now().After(expiresAt.Time) {
-		log.Printf("⚰️ Link %s expired at %v", code, expiresAt.Time)
-		return "", false
+		slog.InfoContext(ctx, "⚰️ Link expired", "code", code, "expired_at", expiresAt.Time)
+		r.cacheLink(ctx, cacheKey, cachedLink{Expired: true}, negativeCacheTTL)
+		return "", ErrExpired
+	}
+	if clicksLeft.Valid && clicksLeft.Int64 <= 0 {
+		r.cacheLink(ctx, cacheKey, cachedLink{Exhausted: true}, negativeCacheTTL)
+		return "", ErrExhausted
+	}
+
+	// ⏳ Not live yet: remember that until the activation time
+	if activatesAt.Valid && time.Now().Before(activatesAt.Time) {
+		r.cacheLink(ctx, cacheKey, cachedLink{DomainID: linkDomain, Pending: true}, cacheTTL(activatesAt.Time))
+		if linkDomain != domainID {
+			return "", ErrNotFound
+		}
+		return "", ErrNotActive
 	}
 
 	// 3️⃣ cache result in Redis (set TTL to min(24h, remaining validity))
-	ttl := 24 * time.Hour
+	ttl := linkCacheTTL
 	if expiresAt.Valid {
-		remaining := time.Until(expiresAt.Time)
-		if remaining > 0 && remaining < ttl {
-			ttl = remaining
+		ttl = cacheTTL(expiresAt.Time)
+	}
+	if protected {
+		// 🔒 never put a protected destination in the cache
+		r.cacheLink(ctx, cacheKey, cachedLink{DomainID: linkDomain, Protected: true}, ttl)
+	} else {
+		r.cacheLink(ctx, cacheKey, cachedLink{URL: u, DomainID: linkDomain, Limited: clicksLeft.Valid}, ttl)
+	}
+
+	// 🌐 Codes only resolve on the domain they were issued for
+	if linkDomain != domainID {
+		return "", ErrNotFound
+	}
+	if protected {
+		return "", ErrPasswordRequired
+	}
+	if clicksLeft.Valid {
+		if err := r.spendClick(ctx, cacheKey, code); err != nil {
+			return "", err
 		}
 	}
-	cache.Set(cacheKey, u, ttl)
+	return u, nil
+}
+
+// spendClick spends a click of a limited link and marks its cache entry
+// Exhausted once the last one is gone.
+func (r *PostgresRepo) spendClick(ctx context.Context, cacheKey, code string) error {
+	err := r.ConsumeClick(ctx, code)
+	if errors.Is(err, ErrExhausted) {
+		r.cacheLink(ctx, cacheKey, cachedLink{Exhausted: true}, negativeCacheTTL)
+	}
+	return err
+}
+
+// ConsumeClick atomically spends one click of a click-limited link. The
+// conditional UPDATE serializes concurrent redirects on the row lock, so a
+// link with N clicks is followed at most N times. Unlimited links are a no-op.
+func (r *PostgresRepo) ConsumeClick(ctx context.Context, code string) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE links SET clicks_left = clicks_left - 1
+		WHERE code = $1 AND clicks_left > 0
+	`, code)
+	if err != nil {
+		return dbError(ctx, "ConsumeClick", err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+
+	var limited bool
+	err = r.db.QueryRowContext(ctx,
+		`SELECT clicks_left IS NOT NULL FROM links WHERE code = $1`, code,
+	).Scan(&limited)
+	if err != nil {
+		return dbError(ctx, "ConsumeClick check", err)
+	}
+	if limited {
+		return ErrExhausted
+	}
+	return nil
+}
 
-	return u, true
+// GetProtectedURL returns the destination and password hash of a protected
+// code served on domainID, straight from Postgres.
+func (r *PostgresRepo) GetProtectedURL(ctx context.Context, code string, domainID int) (string, string, error) {
+	var u, hash string
+	var expiresAt, activatesAt sql.NullTime
+	var clicksLeft sql.NullInt64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT long_url, password_hash, expires_at, activates_at, clicks_left
+		FROM links
+		WHERE code = $1 AND COALESCE(domain_id, 0) = $2 AND password_hash IS NOT NULL
+	`, code, domainID).Scan(&u, &hash, &expiresAt, &activatesAt, &clicksLeft)
+	if err != nil {
+		return "", "", dbError(ctx, "GetProtectedURL", err)
+	}
+	if expiresAt.Valid && time.Now().After(expiresAt.Time) {
+		return "", "", ErrExpired
+	}
+	if activatesAt.Valid && time.Now().Before(activatesAt.Time) {
+		return "", "", ErrNotActive
+	}
+	if clicksLeft.Valid && clicksLeft.Int64 <= 0 {
+		return "", "", ErrExhausted
+	}
+	return u, hash, nil
+}
+
+// cacheTTL caps a cache entry at linkCacheTTL and at the next boundary
+// (activation or expiry) of the link, whichever comes first.
+func cacheTTL(boundary time.Time) time.Duration {
+	remaining := time.Until(boundary)
+	if remaining > 0 && remaining < linkCacheTTL {
+		return remaining
+	}
+	return linkCacheTTL
+}
+
+func (r *PostgresRepo) cacheLink(ctx context.Context, key string, c cachedLink, ttl time.Duration) {
+	data, _ := json.Marshal(c)
+	r.cache.Set(ctx, key, string(data), ttl)
 }
 
 // GetTopDomains returns top N most frequently saved domains for a specific user
-func (r *PostgresRepo) GetTopDomains(userID, n int) map[string]int {
+func (r *PostgresRepo) GetTopDomains(ctx context.Context, userID, n int) (map[string]int, error) {
 	cacheKey := fmt.Sprintf("metrics:topdomains:%d", userID)
 
 	// 1️⃣ Try Redis cache
-	if cachedJSON, ok := cache.Get(cacheKey); ok {
+	if cachedJSON, ok := r.cache.Get(ctx, cacheKey); ok {
 		out := make(map[string]int)
 		if err := json.Unmarshal([]byte(cachedJSON), &out); err == nil {
-			return out
+			return out, nil
 		}
 	}
 
+	if r.cacheDown(r.MetricsCachePolicy) {
+		return nil, fmt.Errorf("GetTopDomains: %w: cache down", ErrUnavailable)
+	}
+
 	// 2️⃣ Query DB if cache miss
-	rows, err := r.db.Query(`
+	rows, err := r.db.QueryContext(ctx, `
 		SELECT domain, count FROM domain_counts
 		WHERE user_id = $1
 		ORDER BY count DESC
 		LIMIT $2
 	`, userID, n)
 	if err != nil {
-		log.Printf("❌ GetTopDomains error: %v", err)
-		return map[string]int{}
+		return nil, dbError(ctx, "GetTopDomains", err)
 	}
 	defer rows.Close()
 
@@ -164,98 +391,250 @@ func (r *PostgresRepo) GetTopDomains(userID, n int) map[string]int {
 			out[domain] = count
 		}
 	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(ctx, "GetTopDomains", err)
+	}
 
 	// 3️⃣ Save to Redis for 10 minutes
 	data, _ := json.Marshal(out)
-	cache.Set(cacheKey, string(data), 10*time.Minute)
+	r.cache.Set(ctx, cacheKey, string(data), 10*time.Minute)
 
-	return out
+	return out, nil
 }
 
 // IncrementDomainCount increases count for a given domain (user-specific)
-func (r *PostgresRepo) IncrementDomainCount(u string, userID int) {
+func (r *PostgresRepo) IncrementDomainCount(ctx context.Context, u string, userID int) error {
 	domain := extractDomain(u)
 	if domain == "" {
-		return
+		return nil
 	}
-	_, err := r.db.Exec(`
+	_, err := r.db.ExecContext(ctx, `
 		INSERT INTO domain_counts (domain, user_id, count)
 		VALUES ($1, $2, 1)
 		ON CONFLICT (domain, user_id)
 		DO UPDATE SET count = domain_counts.count + 1
 	`, domain, userID)
 	if err != nil {
-		log.Printf("❌ IncrementDomainCount error: %v", err)
+		return dbError(ctx, "IncrementDomainCount", err)
 	}
 
-	cache.Delete(fmt.Sprintf("metrics:topdomains:%d", userID))
+	r.invalidate(ctx, fmt.Sprintf("metrics:topdomains:%d", userID))
+	return nil
 }
 
 // GetAllURLsByUser returns all shortened URLs for a given user
-func (r *PostgresRepo) GetAllURLsByUser(userID int) []map[string]string {
-	rows, err := r.db.Query(`
-		SELECT code, long_url, created_at, expires_at
-		FROM links
-		WHERE user_id = $1
-		ORDER BY created_at DESC
+func (r *PostgresRepo) GetAllURLsByUser(ctx context.Context, userID int) ([]models.Link, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT l.code, l.long_url, l.shared, COALESCE(l.domain_id, 0), COALESCE(d.hostname, ''),
+		       l.created_at, l.expires_at, l.activates_at
+		FROM links l
+		LEFT JOIN domains d ON d.id = l.domain_id
+		WHERE l.user_id = $1
+		ORDER BY l.created_at DESC
 	`, userID)
 	if err != nil {
-		log.Printf("❌ GetAllURLsByUser error: %v", err)
-		return nil
+		return nil, dbError(ctx, "GetAllURLsByUser", err)
 	}
 	defer rows.Close()
 
-	var results []map[string]string
+	var results []models.Link
 	for rows.Next() {
-		var code, longURL string
-		var createdAt time.Time
-		var expiresAt sql.NullTime
-		if err := rows.Scan(&code, &longURL, &createdAt, &expiresAt); err == nil {
-			expiry := ""
-			if expiresAt.Valid {
-				expiry = expiresAt.Time.Format(time.RFC3339)
+		l := models.Link{UserID: userID}
+		var expiresAt, activatesAt sql.NullTime
+		if err := rows.Scan(&l.Code, &l.LongURL, &l.Shared, &l.DomainID, &l.Domain, &l.CreatedAt, &expiresAt, &activatesAt); err == nil {
+			l.ExpiresAt = nullTimePtr(expiresAt)
+			l.ActivatesAt = nullTimePtr(activatesAt)
+			results = append(results, l)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(ctx, "GetAllURLsByUser", err)
+	}
+	return results, nil
+}
+
+// sweptLink is a link removed by the expiry sweep.
+type sweptLink struct {
+	code    string
+	userID  sql.NullInt64
+	longURL string
+}
+
+// CleanupExpiredLinks deletes expired links in batches of batchSize and
+// returns how many were removed. Each batch also drops the links' clicks and
+// rollups, decrements their owners' domain_counts and evicts their cache keys,
+// like DeleteLink does. Rows locked by a concurrent edit are skipped until
+// the next sweep.
+func (r *PostgresRepo) CleanupExpiredLinks(ctx context.Context, batchSize int) (int, error) {
+	total := 0
+	for {
+		n, err := r.cleanupExpiredBatch(ctx, batchSize)
+		total += n
+		if err != nil || n < batchSize {
+			if total > 0 {
+				slog.InfoContext(ctx, "🧹 Removed expired links", "links", total)
 			}
-			results = append(results, map[string]string{
-				"short_url":  "http://localhost:8080/" + code,
-				"long_url":   longURL,
-				"created_at": createdAt.Format(time.RFC3339),
-				"expires_at": expiry,
-			})
+			return total, err
 		}
 	}
-	return results
 }
 
-// 🧹 Optional: CleanupExpiredLinks removes expired links from DB & cache.
-func (r *PostgresRepo) CleanupExpiredLinks() {
-	ctx := context.Background()
-	res, err := r.db.ExecContext(ctx, `
+func (r *PostgresRepo) cleanupExpiredBatch(ctx context.Context, batchSize int) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, dbError(ctx, "CleanupExpiredLinks begin", err)
+	}
+	defer tx.Rollback()
+
+	// 1️⃣ Delete one batch
+	rows, err := tx.QueryContext(ctx, `
 		DELETE FROM links
-		WHERE expires_at IS NOT NULL AND expires_at < NOW()
-	`)
+		WHERE id IN (
+			SELECT id FROM links
+			WHERE expires_at IS NOT NULL AND expires_at < NOW()
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING code, user_id, long_url
+	`, batchSize)
 	if err != nil {
-		log.Printf("❌ CleanupExpiredLinks error: %v", err)
-		return
+		return 0, dbError(ctx, "CleanupExpiredLinks delete", err)
 	}
-	rows, _ := res.RowsAffected()
-	log.Printf("🧹 CleanupExpiredLinks removed %d expired links", rows)
+	var swept []sweptLink
+	for rows.Next() {
+		var l sweptLink
+		if err := rows.Scan(&l.code, &l.userID, &l.longURL); err != nil {
+			rows.Close()
+			return 0, dbError(ctx, "CleanupExpiredLinks scan", err)
+		}
+		swept = append(swept, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, dbError(ctx, "CleanupExpiredLinks delete", err)
+	}
+	if len(swept) == 0 {
+		return 0, nil
+	}
+
+	// 2️⃣ Decrement domain counts per owner and domain
+	type ownerDomain struct {
+		userID int64
+		domain string
+	}
+	decrements := make(map[ownerDomain]int)
+	codes := make([]string, 0, len(swept))
+	keys := make([]string, 0, len(swept))
+	owners := make(map[int64]bool)
+	for _, l := range swept {
+		codes = append(codes, l.code)
+		keys = append(keys, "shorturl:"+l.code)
+		if !l.userID.Valid {
+			continue
+		}
+		owners[l.userID.Int64] = true
+		if domain := extractDomain(l.longURL); domain != "" {
+			decrements[ownerDomain{l.userID.Int64, domain}]++
+		}
+	}
+	for od, n := range decrements {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE domain_counts SET count = GREATEST(count - $3, 0)
+			WHERE user_id = $1 AND domain = $2
+		`, od.userID, od.domain, n); err != nil {
+			return 0, dbError(ctx, "CleanupExpiredLinks domain decrement", err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM domain_counts
+			WHERE user_id = $1 AND domain = $2 AND count = 0
+		`, od.userID, od.domain); err != nil {
+			return 0, dbError(ctx, "CleanupExpiredLinks domain_counts delete", err)
+		}
+	}
+
+	// 3️⃣ Drop click history so reused codes start clean
+	if _, err := tx.ExecContext(ctx, `DELETE FROM clicks WHERE code = ANY($1)`, pq.Array(codes)); err != nil {
+		return 0, dbError(ctx, "CleanupExpiredLinks clicks delete", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM click_rollups WHERE code = ANY($1)`, pq.Array(codes)); err != nil {
+		return 0, dbError(ctx, "CleanupExpiredLinks rollups delete", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, dbError(ctx, "CleanupExpiredLinks commit", err)
+	}
+
+	// 4️⃣ Invalidate caches
+	for userID := range owners {
+		keys = append(keys, fmt.Sprintf("metrics:topdomains:%d", userID))
+	}
+	r.invalidate(ctx, keys...)
+	return len(swept), nil
 }
 
-func (r *PostgresRepo) DeleteLink(userID int, code string) bool {
-	ctx := context.Background()
+// RollupClicks recomputes per-day click totals and unique visitors for
+// every UTC day from since's day onwards. Recomputing is idempotent, so a
+// rollup that overlaps the previous one just refreshes its numbers. Days
+// missed while the job wasn't running are rolled up as well, and once the
+// run commits every day before today counts as rolled up for GetLinkStats.
+func (r *PostgresRepo) RollupClicks(ctx context.Context, since time.Time) error {
+	day := since.UTC().Truncate(24 * time.Hour)
+	today := time.Now().UTC().Truncate(24 * time.Hour)
 
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return dbError(ctx, "RollupClicks begin", err)
+	}
+	defer tx.Rollback()
+
+	var rolledBefore time.Time
+	err = tx.QueryRowContext(ctx, `SELECT rolled_before FROM click_rollup_state FOR UPDATE`).Scan(&rolledBefore)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		day = time.Time{} // first run: roll up the whole history
+	case err != nil:
+		return dbError(ctx, "RollupClicks state", err)
+	case rolledBefore.Before(day):
+		day = rolledBefore.UTC()
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO click_rollups (code, day, clicks, unique_visitors)
+		SELECT code, (clicked_at AT TIME ZONE 'UTC')::date, COUNT(*), COUNT(DISTINCT ip_hash)
+		FROM clicks
+		WHERE clicked_at >= $1
+		GROUP BY 1, 2
+		ON CONFLICT (code, day)
+		DO UPDATE SET clicks = EXCLUDED.clicks, unique_visitors = EXCLUDED.unique_visitors
+	`, day)
+	if err != nil {
+		return dbError(ctx, "RollupClicks", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO click_rollup_state (id, rolled_before)
+		VALUES (TRUE, $1::date)
+		ON CONFLICT (id)
+		DO UPDATE SET rolled_before = GREATEST(click_rollup_state.rolled_before, EXCLUDED.rolled_before)
+	`, today.Format(time.DateOnly)); err != nil {
+		return dbError(ctx, "RollupClicks state", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return dbError(ctx, "RollupClicks commit", err)
+	}
+	n, _ := res.RowsAffected()
+	slog.InfoContext(ctx, "📈 Refreshed click rollups", "link_days", n, "since", day.Format(time.DateOnly))
+	return nil
+}
+
+func (r *PostgresRepo) DeleteLink(ctx context.Context, userID int, code string) error {
 	// 1️⃣ Find the long URL before deleting
 	var longURL string
 	err := r.db.QueryRowContext(ctx,
 		`SELECT long_url FROM links WHERE code = $1 AND user_id = $2`,
 		code, userID,
 	).Scan(&longURL)
-	if err == sql.ErrNoRows {
-		return false
-	}
 	if err != nil {
-		log.Printf("❌ DeleteLink select error: %v", err)
-		return false
+		return dbError(ctx, "DeleteLink select", err)
 	}
 
 	// Normalize domain (same logic used in Save)
@@ -267,53 +646,271 @@ func (r *PostgresRepo) DeleteLink(userID int, code string) bool {
 		code, userID,
 	)
 	if err != nil {
-		log.Printf("❌ DeleteLink delete error: %v", err)
-		return false
+		return dbError(ctx, "DeleteLink delete", err)
 	}
 
 	rows, _ := res.RowsAffected()
 	if rows == 0 {
-		return false
+		return ErrNotFound
 	}
 
 	// 3️⃣ Adjust domain_counts cleanly
 	if domain != "" {
 		var remaining int
 		err = r.db.QueryRowContext(ctx, `
-			SELECT COUNT(*) 
-			FROM links 
+			SELECT COUNT(*)
+			FROM links
 			WHERE user_id = $1 AND POSITION($2 IN long_url) > 0
 		`, userID, domain).Scan(&remaining)
 		if err != nil {
-			log.Printf("❌ DeleteLink domain count check error: %v", err)
+			slog.ErrorContext(ctx, "❌ DeleteLink domain count check error", "err", err)
 		}
 
 		if remaining > 0 {
 			// Decrement safely
 			_, err = r.db.ExecContext(ctx, `
-				UPDATE domain_counts 
+				UPDATE domain_counts
 				SET count = GREATEST(count - 1, 0)
 				WHERE user_id = $1 AND domain = $2
 			`, userID, domain)
 			if err != nil {
-				log.Printf("❌ DeleteLink domain decrement error: %v", err)
+				slog.ErrorContext(ctx, "❌ DeleteLink domain decrement error", "err", err)
 			}
 		} else {
 			// Remove domain entry entirely if no links left
 			_, err = r.db.ExecContext(ctx, `
-				DELETE FROM domain_counts 
+				DELETE FROM domain_counts
 				WHERE user_id = $1 AND domain = $2
 			`, userID, domain)
 			if err != nil {
-				log.Printf("❌ DeleteLink domain_counts delete error: %v", err)
+				slog.ErrorContext(ctx, "❌ DeleteLink domain_counts delete error", "err", err)
 			}
 		}
 	}
 
+	// Drop click history so a reused code starts clean
+	if _, err = r.db.ExecContext(ctx, `DELETE FROM clicks WHERE code = $1`, code); err != nil {
+		slog.ErrorContext(ctx, "❌ DeleteLink clicks delete error", "err", err)
+	}
+	if _, err = r.db.ExecContext(ctx, `DELETE FROM click_rollups WHERE code = $1`, code); err != nil {
+		slog.ErrorContext(ctx, "❌ DeleteLink rollups delete error", "err", err)
+	}
+
 	// 4️⃣ Invalidate caches
-	cache.Delete("shorturl:" + code)
-	cache.Delete(fmt.Sprintf("metrics:topdomains:%d", userID))
+	r.invalidate(ctx, "shorturl:"+code, fmt.Sprintf("metrics:topdomains:%d", userID))
+
+	slog.InfoContext(ctx, "🗑️ Deleted link", "code", code, "user_id", userID, "domain", domain)
+	return nil
+}
+
+// UpdateLink edits a link owned by userID in one transaction. When the
+// destination host changes, the user's domain_counts move with it.
+func (r *PostgresRepo) UpdateLink(ctx context.Context, userID int, code string, upd models.LinkUpdate) (*models.Link, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, dbError(ctx, "UpdateLink begin", err)
+	}
+	defer tx.Rollback()
+
+	// 1️⃣ Lock the current row
+	l := models.Link{Code: code, UserID: userID}
+	var expiresAt, activatesAt sql.NullTime
+	var clicksLeft sql.NullInt64
+	err = tx.QueryRowContext(ctx, `
+		SELECT l.long_url, l.shared, COALESCE(l.domain_id, 0), COALESCE(d.hostname, ''), l.created_at,
+		       l.expires_at, l.activates_at, l.clicks_left
+		FROM links l
+		LEFT JOIN domains d ON d.id = l.domain_id
+		WHERE l.code = $1 AND l.user_id = $2
+		FOR UPDATE OF l
+	`, code, userID).Scan(&l.LongURL, &l.Shared, &l.DomainID, &l.Domain, &l.CreatedAt, &expiresAt, &activatesAt, &clicksLeft)
+	if err != nil {
+		return nil, dbError(ctx, "UpdateLink select", err)
+	}
+	l.ExpiresAt = nullTimePtr(expiresAt)
+	l.ActivatesAt = nullTimePtr(activatesAt)
+	l.ClicksLeft = nullIntPtr(clicksLeft)
+
+	oldDomain := extractDomain(l.LongURL)
+	if upd.LongURL != nil {
+		l.LongURL = *upd.LongURL
+	}
+	if upd.Shared != nil {
+		l.Shared = *upd.Shared
+	}
+	if upd.SetExpiry {
+		if expiresBeforeActivation(upd.ExpiresAt, l.ActivatesAt) {
+			return nil, ErrExpiryBeforeActivation
+		}
+		l.ExpiresAt = upd.ExpiresAt
+	}
+
+	// 2️⃣ Write the new values
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE links SET long_url = $3, shared = $4, expires_at = $5
+		WHERE code = $1 AND user_id = $2
+	`, code, userID, l.LongURL, l.Shared, l.ExpiresAt); err != nil {
+		return nil, dbError(ctx, "UpdateLink update", err)
+	}
+
+	// 3️⃣ Move the domain count if the destination host changed
+	if newDomain := extractDomain(l.LongURL); newDomain != oldDomain {
+		if oldDomain != "" {
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE domain_counts SET count = GREATEST(count - 1, 0)
+				WHERE user_id = $1 AND domain = $2
+			`, userID, oldDomain); err != nil {
+				return nil, dbError(ctx, "UpdateLink domain decrement", err)
+			}
+			if _, err := tx.ExecContext(ctx, `
+				DELETE FROM domain_counts
+				WHERE user_id = $1 AND domain = $2 AND count = 0
+			`, userID, oldDomain); err != nil {
+				return nil, dbError(ctx, "UpdateLink domain_counts delete", err)
+			}
+		}
+		if newDomain != "" {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO domain_counts (domain, user_id, count)
+				VALUES ($1, $2, 1)
+				ON CONFLICT (domain, user_id)
+				DO UPDATE SET count = domain_counts.count + 1
+			`, newDomain, userID); err != nil {
+				return nil, dbError(ctx, "UpdateLink domain increment", err)
+			}
+		}
+	}
 
-	log.Printf("🗑️ Deleted link %s for user %d (domain=%s)", code, userID, domain)
-	return true
+	if err := tx.Commit(); err != nil {
+		return nil, dbError(ctx, "UpdateLink commit", err)
+	}
+
+	// 4️⃣ Invalidate caches so redirects pick up the new destination/expiry
+	r.invalidate(ctx, "shorturl:"+code, fmt.Sprintf("metrics:topdomains:%d", userID))
+
+	slog.InfoContext(ctx, "✏️ Updated link", "code", code, "user_id", userID)
+	return &l, nil
+}
+
+// SaveClicks bulk-loads a batch of click events with COPY in a single transaction.
+func (r *PostgresRepo) SaveClicks(ctx context.Context, events []models.ClickEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return dbError(ctx, "SaveClicks begin", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("clicks",
+		"code", "clicked_at", "referrer", "user_agent", "ip_hash", "country"))
+	if err != nil {
+		return dbError(ctx, "SaveClicks prepare", err)
+	}
+
+	for _, ev := range events {
+		_, err := stmt.ExecContext(ctx, ev.Code, ev.ClickedAt,
+			nullIfEmpty(ev.Referrer), nullIfEmpty(ev.UserAgent), nullIfEmpty(ev.IPHash), nullIfEmpty(ev.Country))
+		if err != nil {
+			stmt.Close()
+			return dbError(ctx, "SaveClicks copy", err)
+		}
+	}
+	// an empty Exec flushes the COPY buffer
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return dbError(ctx, "SaveClicks copy flush", err)
+	}
+	if err := stmt.Close(); err != nil {
+		return dbError(ctx, "SaveClicks copy close", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return dbError(ctx, "SaveClicks commit", err)
+	}
+	return nil
+}
+
+// nullIfEmpty maps "" to SQL NULL for optional text columns.
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// GetLinkStats returns totals and a time-bucketed click series for a link owned by userID.
+func (r *PostgresRepo) GetLinkStats(ctx context.Context, userID int, code string, since time.Time, bucket string) (*models.LinkStats, error) {
+	var owned bool
+	err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM links WHERE code = $1 AND user_id = $2)`,
+		code, userID,
+	).Scan(&owned)
+	if err != nil {
+		return nil, dbError(ctx, "GetLinkStats ownership", err)
+	}
+	if !owned {
+		return nil, ErrNotFound
+	}
+
+	// Days the rollup job has finished come from click_rollups; later clicks,
+	// and every hourly bucket, are read from the raw table.
+	var rolledBefore time.Time
+	err = r.db.QueryRowContext(ctx, `SELECT rolled_before FROM click_rollup_state`).Scan(&rolledBefore)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, dbError(ctx, "GetLinkStats rollup state", err)
+	}
+	rolledBefore = rolledBefore.UTC()
+
+	stats := &models.LinkStats{Code: code, Bucket: bucket, Since: since}
+	err = r.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(clicks), 0), COALESCE(SUM(unique_visitors), 0)
+		FROM (
+			SELECT clicks, unique_visitors
+			FROM click_rollups
+			WHERE code = $1 AND day < $2::date
+			UNION ALL
+			SELECT COUNT(*), COUNT(DISTINCT ip_hash)
+			FROM clicks
+			WHERE code = $1 AND clicked_at >= $3
+			GROUP BY (clicked_at AT TIME ZONE 'UTC')::date
+		) days
+	`, code, rolledBefore.Format(time.DateOnly), rolledBefore).Scan(&stats.TotalClicks, &stats.UniqueVisitors)
+	if err != nil {
+		return nil, dbError(ctx, "GetLinkStats totals", err)
+	}
+
+	rawFrom := since
+	if bucket == BucketDay && rolledBefore.After(rawFrom) {
+		rawFrom = rolledBefore
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT day::timestamp, clicks, unique_visitors
+		FROM click_rollups
+		WHERE code = $1 AND day >= $3::date AND day < $4::date
+		UNION ALL
+		SELECT date_trunc($2, clicked_at AT TIME ZONE 'UTC'), COUNT(*), COUNT(DISTINCT ip_hash)
+		FROM clicks
+		WHERE code = $1 AND clicked_at >= $5
+		GROUP BY 1
+	`, code, bucket, since.UTC().Format(time.DateOnly), rawFrom.UTC().Format(time.DateOnly), rawFrom)
+	if err != nil {
+		return nil, dbError(ctx, "GetLinkStats series", err)
+	}
+	defer rows.Close()
+
+	points := make(map[time.Time]models.StatsBucket)
+	for rows.Next() {
+		var p models.StatsBucket
+		if err := rows.Scan(&p.Start, &p.Clicks, &p.Unique); err == nil {
+			p.Start = truncateToBucket(p.Start, bucket)
+			points[p.Start] = p
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(ctx, "GetLinkStats series", err)
+	}
+	stats.Series = fillSeries(points, since, time.Now(), bucket)
+	return stats, nil
 }
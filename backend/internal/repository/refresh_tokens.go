@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/brij-812/HyperLinkOS/internal/models"
+)
+
+// ErrTokenRevoked is returned by RotateRefreshToken when the token was
+// revoked in the meantime, i.e. a concurrent refresh already rotated it.
+var ErrTokenRevoked = fmt.Errorf("refresh token already revoked: %w", ErrConflict)
+
+// RefreshTokenStore keeps refresh token families. Rotation policy (expiry,
+// reuse detection) is up to the caller; the store only makes each step atomic.
+type RefreshTokenStore interface {
+	// CreateRefreshToken stores t and sets its ID.
+	CreateRefreshToken(ctx context.Context, t *models.RefreshToken) error
+	// GetRefreshToken returns the token with tokenHash, revoked or not,
+	// along with its owner's email and plan.
+	GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	// RotateRefreshToken revokes live token id and stores next as its
+	// replacement, in one transaction. ErrTokenRevoked if id was not live.
+	RotateRefreshToken(ctx context.Context, id int64, next *models.RefreshToken) error
+	// RevokeRefreshFamily revokes every live token of familyID.
+	RevokeRefreshFamily(ctx context.Context, familyID string) error
+}
+
+// ---------------------------------------------------------------------------
+// Postgres
+// ---------------------------------------------------------------------------
+
+func (r *PostgresRepo) CreateRefreshToken(ctx context.Context, t *models.RefreshToken) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, t.UserID, t.FamilyID, t.TokenHash, t.ExpiresAt).Scan(&t.ID)
+	return dbError(ctx, "CreateRefreshToken", err)
+}
+
+func (r *PostgresRepo) GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	t := &models.RefreshToken{TokenHash: tokenHash}
+	var revokedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, `
+		SELECT rt.id, rt.user_id, rt.family_id, rt.expires_at, rt.revoked_at, u.email, u.plan
+		FROM refresh_tokens rt
+		JOIN users u ON u.id = rt.user_id
+		WHERE rt.token_hash = $1
+	`, tokenHash).Scan(&t.ID, &t.UserID, &t.FamilyID, &t.ExpiresAt, &revokedAt, &t.Email, &t.Plan)
+	if err != nil {
+		return nil, dbError(ctx, "GetRefreshToken", err)
+	}
+	t.RevokedAt = nullTimePtr(revokedAt)
+	return t, nil
+}
+
+func (r *PostgresRepo) RotateRefreshToken(ctx context.Context, id int64, next *models.RefreshToken) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return dbError(ctx, "RotateRefreshToken begin", err)
+	}
+	defer tx.Rollback()
+
+	// the row lock taken here makes a concurrent rotation of id wait, then match nothing
+	res, err := tx.ExecContext(ctx, `
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL
+	`, id)
+	if err != nil {
+		return dbError(ctx, "RotateRefreshToken revoke", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrTokenRevoked
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, next.UserID, next.FamilyID, next.TokenHash, next.ExpiresAt).Scan(&next.ID)
+	if err != nil {
+		return dbError(ctx, "RotateRefreshToken insert", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE refresh_tokens SET replaced_by = $2 WHERE id = $1
+	`, id, next.ID); err != nil {
+		return dbError(ctx, "RotateRefreshToken link", err)
+	}
+	return dbError(ctx, "RotateRefreshToken commit", tx.Commit())
+}
+
+func (r *PostgresRepo) RevokeRefreshFamily(ctx context.Context, familyID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE family_id = $1 AND revoked_at IS NULL
+	`, familyID)
+	return dbError(ctx, "RevokeRefreshFamily", err)
+}
+
+// ---------------------------------------------------------------------------
+// Memory
+// ---------------------------------------------------------------------------
+
+func (r *MemoryRepo) CreateRefreshToken(_ context.Context, t *models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.insertRefreshToken(t)
+}
+
+// insertRefreshToken stores a copy of t; callers hold r.mu.
+func (r *MemoryRepo) insertRefreshToken(t *models.RefreshToken) error {
+	for _, other := range r.refreshTokens {
+		if other.TokenHash == t.TokenHash {
+			return fmt.Errorf("CreateRefreshToken: %w", ErrConflict)
+		}
+	}
+	r.refreshSeq++
+	t.ID = r.refreshSeq
+	stored := *t
+	stored.RevokedAt = nil
+	r.refreshTokens[t.ID] = &stored
+	return nil
+}
+
+// GetRefreshToken leaves Email and Plan empty: MemoryRepo keeps no users.
+func (r *MemoryRepo) GetRefreshToken(_ context.Context, tokenHash string) (*models.RefreshToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, t := range r.refreshTokens {
+		if t.TokenHash == tokenHash {
+			out := *t
+			return &out, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryRepo) RotateRefreshToken(_ context.Context, id int64, next *models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.refreshTokens[id]
+	if !ok || t.RevokedAt != nil {
+		return ErrTokenRevoked
+	}
+	if err := r.insertRefreshToken(next); err != nil {
+		return err
+	}
+	now := time.Now()
+	t.RevokedAt = &now
+	return nil
+}
+
+func (r *MemoryRepo) RevokeRefreshFamily(_ context.Context, familyID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, t := range r.refreshTokens {
+		if t.FamilyID == familyID && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+	return nil
+}
@@ -1,13 +1,146 @@
 package repository
 
-import "time"
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/brij-812/HyperLinkOS/internal/models"
+	"github.com/lib/pq"
+)
+
+// Typed errors returned by every Repository implementation. Handlers map
+// them onto HTTP statuses, so a storage outage is never reported as a miss.
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrExpired     = errors.New("link expired")
+	ErrConflict    = errors.New("conflict")
+	ErrUnavailable = errors.New("storage unavailable")
+	// ErrPasswordRequired is returned by GetURL for password-protected links;
+	// their destination is only available through GetProtectedURL.
+	ErrPasswordRequired = errors.New("password required")
+	// ErrExhausted is returned for a click-limited link with no clicks left.
+	ErrExhausted = errors.New("click limit reached")
+	// ErrNotActive is returned for a scheduled link before its activation time.
+	ErrNotActive = errors.New("link not active yet")
+	// ErrExpiryBeforeActivation is returned by UpdateLink for an expiry that
+	// isn't after the link's activation time: the link would never go live.
+	ErrExpiryBeforeActivation = errors.New("expiry must be after the activation time")
+)
+
+// ErrCodeTaken is returned by Save when the short code already belongs to another link.
+var ErrCodeTaken = fmt.Errorf("short code already in use: %w", ErrConflict)
 
 type Repository interface {
-	Save(u, code string, userID int, expiresAt *time.Time)
-	GetCode(u string) (string, bool)
-	GetURL(code string) (string, bool)
-	GetTopDomains(userID, n int) map[string]int
-	IncrementDomainCount(u string, userID int)
-	GetAllURLsByUser(userID int) []map[string]string
-	DeleteLink(userID int, code string) bool
+	Save(ctx context.Context, link *models.Link) error
+	// GetCode returns the caller's own live, unprotected, unlimited code for a long URL on domainID (0 = default).
+	GetCode(ctx context.Context, u string, userID, domainID int) (string, error)
+	// GetSharedCode returns a live, unprotected, unlimited default-domain code for a long URL whose owner opted in to sharing.
+	GetSharedCode(ctx context.Context, u string) (string, error)
+	// GetURL resolves a code served on domainID. A code issued on another
+	// domain is ErrNotFound; a code past its expiry is ErrExpired, one before
+	// its activation ErrNotActive, and a password-protected one ErrPasswordRequired. Resolving a click-limited
+	// code spends one of its clicks; once none are left it is ErrExhausted.
+	GetURL(ctx context.Context, code string, domainID int) (string, error)
+	// GetProtectedURL resolves a password-protected code like GetURL and also
+	// returns its password hash. It always reads storage, never the cache;
+	// codes without a password are ErrNotFound.
+	GetProtectedURL(ctx context.Context, code string, domainID int) (longURL, passwordHash string, err error)
+	// ConsumeClick atomically spends one click of a click-limited link, for
+	// redirects that don't go through GetURL. Unlimited links are a no-op.
+	ConsumeClick(ctx context.Context, code string) error
+	GetTopDomains(ctx context.Context, userID, n int) (map[string]int, error)
+	IncrementDomainCount(ctx context.Context, u string, userID int) error
+	GetAllURLsByUser(ctx context.Context, userID int) ([]models.Link, error)
+	DeleteLink(ctx context.Context, userID int, code string) error
+	// UpdateLink applies upd to a link owned by userID and returns the result.
+	// A new expiry not after the link's activation time is ErrExpiryBeforeActivation.
+	UpdateLink(ctx context.Context, userID int, code string, upd models.LinkUpdate) (*models.Link, error)
+
+	// SaveClicks persists a batch of redirect events.
+	SaveClicks(ctx context.Context, events []models.ClickEvent) error
+	// GetLinkStats returns click analytics for a link owned by userID,
+	// bucketed by BucketHour or BucketDay from since until now.
+	GetLinkStats(ctx context.Context, userID int, code string, since time.Time, bucket string) (*models.LinkStats, error)
+}
+
+// Store is everything the server needs from a storage backend: links,
+// custom domains, refresh tokens, API keys and the counter behind the
+// sequence code strategy.
+type Store interface {
+	Repository
+	DomainStore
+	RefreshTokenStore
+	APIKeyStore
+	NextID() (int64, error)
+}
+
+var (
+	_ Store = (*PostgresRepo)(nil)
+	_ Store = (*SQLiteRepo)(nil)
+	_ Store = (*MemoryRepo)(nil)
+)
+
+// dbError maps a database/sql error onto the typed errors above and logs
+// anything unexpected with ctx's request ID. Request cancellation is passed
+// through untouched.
+func dbError(ctx context.Context, op string, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrNotFound
+	case errors.Is(err, context.Canceled):
+		return err
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code == "23505": // unique_violation
+			return fmt.Errorf("%s: %w", op, ErrConflict)
+		case pqErr.Code.Class() == "08", // connection exception
+			pqErr.Code.Class() == "53", // insufficient resources
+			pqErr.Code.Class() == "57": // operator intervention (shutdown, statement timeout)
+			logDBError(ctx, op, err)
+			return fmt.Errorf("%s: %w: %w", op, ErrUnavailable, err)
+		}
+		logDBError(ctx, op, err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		logDBError(ctx, op, err)
+		return fmt.Errorf("%s: %w: %w", op, ErrUnavailable, err)
+	}
+
+	logDBError(ctx, op, err)
+	return fmt.Errorf("%s: %w", op, err)
+}
+
+func logDBError(ctx context.Context, op string, err error) {
+	slog.ErrorContext(ctx, "❌ database error", "op", op, "err", err)
+}
+
+// expiresBeforeActivation reports whether a scheduled link would expire
+// before it goes live. A nil expiry never expires.
+func expiresBeforeActivation(expiresAt, activatesAt *time.Time) bool {
+	return expiresAt != nil && activatesAt != nil && !expiresAt.After(*activatesAt)
+}
+
+func nullIntPtr(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	v := int(n.Int64)
+	return &v
 }
@@ -0,0 +1,864 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/brij-812/HyperLinkOS/internal/models"
+)
+
+// SQLiteRepo stores data in a local SQLite file for single-node deployments.
+// Unlike MemoryRepo it survives restarts and honors expiry in SQL.
+//
+// Times are always bound in UTC so the driver's text encoding sorts
+// chronologically and can be compared with plain < / >.
+type SQLiteRepo struct {
+	db *sql.DB
+}
+
+func NewSQLiteRepo(db *sql.DB) *SQLiteRepo {
+	return &SQLiteRepo{db: db}
+}
+
+// sqliteError maps SQLite failures onto the repository's typed errors.
+// Matching on the message keeps this independent of the driver package.
+func sqliteError(ctx context.Context, op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "UNIQUE constraint failed"):
+		return fmt.Errorf("%s: %w", op, ErrConflict)
+	case strings.Contains(msg, "database is locked"), strings.Contains(msg, "SQLITE_BUSY"):
+		logDBError(ctx, op, err)
+		return fmt.Errorf("%s: %w: %w", op, ErrUnavailable, err)
+	}
+	return dbError(ctx, op, err)
+}
+
+func utcNow() time.Time {
+	return time.Now().UTC()
+}
+
+func utcPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+func (r *SQLiteRepo) Save(ctx context.Context, link *models.Link) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return sqliteError(ctx, "Save begin", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO links (code, long_url, user_id, shared, domain_id, created_at, expires_at, password_hash, clicks_left, activates_at)
+		VALUES (?, ?, ?, ?, NULLIF(?, 0), ?, ?, NULLIF(?, ''), ?, ?)
+		ON CONFLICT (code) DO NOTHING
+	`, link.Code, link.LongURL, link.UserID, link.Shared, link.DomainID, utcNow(), utcPtr(link.ExpiresAt), link.PasswordHash, link.ClicksLeft, utcPtr(link.ActivatesAt))
+	if err != nil {
+		return sqliteError(ctx, "Save", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrCodeTaken
+	}
+
+	if err := sqliteBumpDomain(ctx, tx, extractDomain(link.LongURL), link.UserID, 1); err != nil {
+		return err
+	}
+	return sqliteError(ctx, "Save commit", tx.Commit())
+}
+
+// sqliteBumpDomain adds delta to a user's domain count, dropping rows that reach zero.
+func sqliteBumpDomain(ctx context.Context, tx *sql.Tx, domain string, userID, delta int) error {
+	if domain == "" {
+		return nil
+	}
+	if delta > 0 {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO domain_counts (domain, user_id, count)
+			VALUES (?, ?, ?)
+			ON CONFLICT (domain, user_id)
+			DO UPDATE SET count = domain_counts.count + excluded.count
+		`, domain, userID, delta)
+		return sqliteError(ctx, "domain_counts increment", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE domain_counts SET count = MAX(count + ?, 0)
+		WHERE domain = ? AND user_id = ?
+	`, delta, domain, userID); err != nil {
+		return sqliteError(ctx, "domain_counts decrement", err)
+	}
+	_, err := tx.ExecContext(ctx, `
+		DELETE FROM domain_counts
+		WHERE domain = ? AND user_id = ? AND count = 0
+	`, domain, userID)
+	return sqliteError(ctx, "domain_counts delete", err)
+}
+
+// NextID advances the single-row link_code_seq table (sequence code strategy).
+func (r *SQLiteRepo) NextID() (int64, error) {
+	var id int64
+	err := r.db.QueryRow(`UPDATE link_code_seq SET value = value + 1 WHERE id = 1 RETURNING value`).Scan(&id)
+	if err != nil {
+		return 0, sqliteError(context.Background(), "NextID", err)
+	}
+	return id, nil
+}
+
+func (r *SQLiteRepo) GetCode(ctx context.Context, u string, userID, domainID int) (string, error) {
+	var code string
+	err := r.db.QueryRowContext(ctx, `
+		SELECT code FROM links
+		WHERE long_url = ? AND user_id = ?
+		  AND COALESCE(domain_id, 0) = ?
+		  AND password_hash IS NULL AND clicks_left IS NULL
+		  AND (activates_at IS NULL OR activates_at <= ?)
+		  AND (expires_at IS NULL OR expires_at > ?)
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, u, userID, domainID, utcNow(), utcNow()).Scan(&code)
+	if err != nil {
+		return "", sqliteError(ctx, "GetCode", err)
+	}
+	return code, nil
+}
+
+func (r *SQLiteRepo) GetSharedCode(ctx context.Context, u string) (string, error) {
+	var code string
+	err := r.db.QueryRowContext(ctx, `
+		SELECT code FROM links
+		WHERE long_url = ? AND shared AND domain_id IS NULL
+		  AND password_hash IS NULL AND clicks_left IS NULL
+		  AND (activates_at IS NULL OR activates_at <= ?)
+		  AND (expires_at IS NULL OR expires_at > ?)
+		ORDER BY created_at
+		LIMIT 1
+	`, u, utcNow(), utcNow()).Scan(&code)
+	if err != nil {
+		return "", sqliteError(ctx, "GetSharedCode", err)
+	}
+	return code, nil
+}
+
+func (r *SQLiteRepo) GetURL(ctx context.Context, code string, domainID int) (string, error) {
+	var u string
+	var linkDomain int
+	var expiresAt, activatesAt sql.NullTime
+	var protected bool
+	var clicksLeft sql.NullInt64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT long_url, COALESCE(domain_id, 0), expires_at, activates_at, password_hash IS NOT NULL, clicks_left
+		FROM links
+		WHERE code = ?
+	`, code).Scan(&u, &linkDomain, &expiresAt, &activatesAt, &protected, &clicksLeft)
+	if err != nil {
+		return "", sqliteError(ctx, "GetURL", err)
+	}
+	if linkDomain != domainID {
+		return "", ErrNotFound
+	}
+	if expiresAt.Valid && time.Now().After(expiresAt.Time) {
+		return "", ErrExpired
+	}
+	if clicksLeft.Valid && clicksLeft.Int64 <= 0 {
+		return "", ErrExhausted
+	}
+	if activatesAt.Valid && time.Now().Before(activatesAt.Time) {
+		return "", ErrNotActive
+	}
+	if protected {
+		return "", ErrPasswordRequired
+	}
+	if clicksLeft.Valid {
+		if err := r.ConsumeClick(ctx, code); err != nil {
+			return "", err
+		}
+	}
+	return u, nil
+}
+
+// ConsumeClick spends one click of a click-limited link. SQLite runs one
+// writer at a time, so the conditional UPDATE can't overspend.
+func (r *SQLiteRepo) ConsumeClick(ctx context.Context, code string) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE links SET clicks_left = clicks_left - 1
+		WHERE code = ? AND clicks_left > 0
+	`, code)
+	if err != nil {
+		return sqliteError(ctx, "ConsumeClick", err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+
+	var limited bool
+	err = r.db.QueryRowContext(ctx,
+		`SELECT clicks_left IS NOT NULL FROM links WHERE code = ?`, code,
+	).Scan(&limited)
+	if err != nil {
+		return sqliteError(ctx, "ConsumeClick check", err)
+	}
+	if limited {
+		return ErrExhausted
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) GetProtectedURL(ctx context.Context, code string, domainID int) (string, string, error) {
+	var u, hash string
+	var expiresAt, activatesAt sql.NullTime
+	var clicksLeft sql.NullInt64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT long_url, password_hash, expires_at, activates_at, clicks_left
+		FROM links
+		WHERE code = ? AND COALESCE(domain_id, 0) = ? AND password_hash IS NOT NULL
+	`, code, domainID).Scan(&u, &hash, &expiresAt, &activatesAt, &clicksLeft)
+	if err != nil {
+		return "", "", sqliteError(ctx, "GetProtectedURL", err)
+	}
+	if expiresAt.Valid && time.Now().After(expiresAt.Time) {
+		return "", "", ErrExpired
+	}
+	if activatesAt.Valid && time.Now().Before(activatesAt.Time) {
+		return "", "", ErrNotActive
+	}
+	if clicksLeft.Valid && clicksLeft.Int64 <= 0 {
+		return "", "", ErrExhausted
+	}
+	return u, hash, nil
+}
+
+func (r *SQLiteRepo) GetTopDomains(ctx context.Context, userID, n int) (map[string]int, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT domain, count FROM domain_counts
+		WHERE user_id = ?
+		ORDER BY count DESC
+		LIMIT ?
+	`, userID, n)
+	if err != nil {
+		return nil, sqliteError(ctx, "GetTopDomains", err)
+	}
+	defer rows.Close()
+
+	out := make(map[string]int)
+	for rows.Next() {
+		var domain string
+		var count int
+		if err := rows.Scan(&domain, &count); err == nil {
+			out[domain] = count
+		}
+	}
+	return out, sqliteError(ctx, "GetTopDomains", rows.Err())
+}
+
+func (r *SQLiteRepo) IncrementDomainCount(ctx context.Context, u string, userID int) error {
+	domain := extractDomain(u)
+	if domain == "" {
+		return nil
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO domain_counts (domain, user_id, count)
+		VALUES (?, ?, 1)
+		ON CONFLICT (domain, user_id)
+		DO UPDATE SET count = domain_counts.count + 1
+	`, domain, userID)
+	return sqliteError(ctx, "IncrementDomainCount", err)
+}
+
+func (r *SQLiteRepo) GetAllURLsByUser(ctx context.Context, userID int) ([]models.Link, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT l.code, l.long_url, l.shared, COALESCE(l.domain_id, 0), COALESCE(d.hostname, ''),
+		       l.created_at, l.expires_at, l.activates_at
+		FROM links l
+		LEFT JOIN domains d ON d.id = l.domain_id
+		WHERE l.user_id = ?
+		ORDER BY l.created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, sqliteError(ctx, "GetAllURLsByUser", err)
+	}
+	defer rows.Close()
+
+	var results []models.Link
+	for rows.Next() {
+		l := models.Link{UserID: userID}
+		var expiresAt, activatesAt sql.NullTime
+		if err := rows.Scan(&l.Code, &l.LongURL, &l.Shared, &l.DomainID, &l.Domain, &l.CreatedAt, &expiresAt, &activatesAt); err == nil {
+			l.ExpiresAt = nullTimePtr(expiresAt)
+			l.ActivatesAt = nullTimePtr(activatesAt)
+			results = append(results, l)
+		}
+	}
+	return results, sqliteError(ctx, "GetAllURLsByUser", rows.Err())
+}
+
+func (r *SQLiteRepo) DeleteLink(ctx context.Context, userID int, code string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return sqliteError(ctx, "DeleteLink begin", err)
+	}
+	defer tx.Rollback()
+
+	var longURL string
+	err = tx.QueryRowContext(ctx,
+		`SELECT long_url FROM links WHERE code = ? AND user_id = ?`,
+		code, userID,
+	).Scan(&longURL)
+	if err != nil {
+		return sqliteError(ctx, "DeleteLink select", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM links WHERE code = ? AND user_id = ?`, code, userID); err != nil {
+		return sqliteError(ctx, "DeleteLink delete", err)
+	}
+	if err := sqliteBumpDomain(ctx, tx, extractDomain(longURL), userID, -1); err != nil {
+		return err
+	}
+	// Drop click history so a reused code starts clean
+	if _, err := tx.ExecContext(ctx, `DELETE FROM clicks WHERE code = ?`, code); err != nil {
+		return sqliteError(ctx, "DeleteLink clicks delete", err)
+	}
+	return sqliteError(ctx, "DeleteLink commit", tx.Commit())
+}
+
+// CleanupExpiredLinks deletes expired links in batches of batchSize,
+// keeping domain_counts and click history consistent like DeleteLink does.
+func (r *SQLiteRepo) CleanupExpiredLinks(ctx context.Context, batchSize int) (int, error) {
+	total := 0
+	for {
+		n, err := r.cleanupExpiredBatch(ctx, batchSize)
+		total += n
+		if err != nil || n < batchSize {
+			if total > 0 {
+				slog.InfoContext(ctx, "🧹 Removed expired links", "links", total)
+			}
+			return total, err
+		}
+	}
+}
+
+func (r *SQLiteRepo) cleanupExpiredBatch(ctx context.Context, batchSize int) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, sqliteError(ctx, "CleanupExpiredLinks begin", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		DELETE FROM links
+		WHERE id IN (
+			SELECT id FROM links
+			WHERE expires_at IS NOT NULL AND expires_at < ?
+			ORDER BY id
+			LIMIT ?
+		)
+		RETURNING code, user_id, long_url
+	`, utcNow(), batchSize)
+	if err != nil {
+		return 0, sqliteError(ctx, "CleanupExpiredLinks delete", err)
+	}
+	var swept []sweptLink
+	for rows.Next() {
+		var l sweptLink
+		if err := rows.Scan(&l.code, &l.userID, &l.longURL); err != nil {
+			rows.Close()
+			return 0, sqliteError(ctx, "CleanupExpiredLinks scan", err)
+		}
+		swept = append(swept, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, sqliteError(ctx, "CleanupExpiredLinks delete", err)
+	}
+
+	for _, l := range swept {
+		if l.userID.Valid {
+			if err := sqliteBumpDomain(ctx, tx, extractDomain(l.longURL), int(l.userID.Int64), -1); err != nil {
+				return 0, err
+			}
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM clicks WHERE code = ?`, l.code); err != nil {
+			return 0, sqliteError(ctx, "CleanupExpiredLinks clicks delete", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, sqliteError(ctx, "CleanupExpiredLinks commit", err)
+	}
+	return len(swept), nil
+}
+
+func (r *SQLiteRepo) UpdateLink(ctx context.Context, userID int, code string, upd models.LinkUpdate) (*models.Link, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, sqliteError(ctx, "UpdateLink begin", err)
+	}
+	defer tx.Rollback()
+
+	l := models.Link{Code: code, UserID: userID}
+	var expiresAt, activatesAt sql.NullTime
+	var clicksLeft sql.NullInt64
+	err = tx.QueryRowContext(ctx, `
+		SELECT l.long_url, l.shared, COALESCE(l.domain_id, 0), COALESCE(d.hostname, ''), l.created_at,
+		       l.expires_at, l.activates_at, l.clicks_left
+		FROM links l
+		LEFT JOIN domains d ON d.id = l.domain_id
+		WHERE l.code = ? AND l.user_id = ?
+	`, code, userID).Scan(&l.LongURL, &l.Shared, &l.DomainID, &l.Domain, &l.CreatedAt, &expiresAt, &activatesAt, &clicksLeft)
+	if err != nil {
+		return nil, sqliteError(ctx, "UpdateLink select", err)
+	}
+	l.ExpiresAt = nullTimePtr(expiresAt)
+	l.ActivatesAt = nullTimePtr(activatesAt)
+	l.ClicksLeft = nullIntPtr(clicksLeft)
+
+	oldDomain := extractDomain(l.LongURL)
+	if upd.LongURL != nil {
+		l.LongURL = *upd.LongURL
+	}
+	if upd.Shared != nil {
+		l.Shared = *upd.Shared
+	}
+	if upd.SetExpiry {
+		if expiresBeforeActivation(upd.ExpiresAt, l.ActivatesAt) {
+			return nil, ErrExpiryBeforeActivation
+		}
+		l.ExpiresAt = utcPtr(upd.ExpiresAt)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE links SET long_url = ?, shared = ?, expires_at = ?
+		WHERE code = ? AND user_id = ?
+	`, l.LongURL, l.Shared, utcPtr(l.ExpiresAt), code, userID); err != nil {
+		return nil, sqliteError(ctx, "UpdateLink update", err)
+	}
+
+	if newDomain := extractDomain(l.LongURL); newDomain != oldDomain {
+		if err := sqliteBumpDomain(ctx, tx, oldDomain, userID, -1); err != nil {
+			return nil, err
+		}
+		if err := sqliteBumpDomain(ctx, tx, newDomain, userID, 1); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, sqliteError(ctx, "UpdateLink commit", err)
+	}
+	return &l, nil
+}
+
+func (r *SQLiteRepo) SaveClicks(ctx context.Context, events []models.ClickEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return sqliteError(ctx, "SaveClicks begin", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO clicks (code, clicked_at, referrer, user_agent, ip_hash, country)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return sqliteError(ctx, "SaveClicks prepare", err)
+	}
+	defer stmt.Close()
+
+	for _, ev := range events {
+		if _, err := stmt.ExecContext(ctx, ev.Code, ev.ClickedAt.UTC(),
+			nullIfEmpty(ev.Referrer), nullIfEmpty(ev.UserAgent), nullIfEmpty(ev.IPHash), nullIfEmpty(ev.Country)); err != nil {
+			return sqliteError(ctx, "SaveClicks insert", err)
+		}
+	}
+	return sqliteError(ctx, "SaveClicks commit", tx.Commit())
+}
+
+// GetLinkStats counts totals in SQL and buckets the window in Go, since
+// SQLite has no date_trunc.
+func (r *SQLiteRepo) GetLinkStats(ctx context.Context, userID int, code string, since time.Time, bucket string) (*models.LinkStats, error) {
+	var owned bool
+	err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM links WHERE code = ? AND user_id = ?)`,
+		code, userID,
+	).Scan(&owned)
+	if err != nil {
+		return nil, sqliteError(ctx, "GetLinkStats ownership", err)
+	}
+	if !owned {
+		return nil, ErrNotFound
+	}
+
+	stats := &models.LinkStats{Code: code, Bucket: bucket, Since: since}
+	err = r.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(clicks), 0), COALESCE(SUM(visitors), 0)
+		FROM (
+			SELECT COUNT(*) AS clicks, COUNT(DISTINCT ip_hash) AS visitors
+			FROM clicks
+			WHERE code = ?
+			GROUP BY date(clicked_at)
+		)
+	`, code).Scan(&stats.TotalClicks, &stats.UniqueVisitors)
+	if err != nil {
+		return nil, sqliteError(ctx, "GetLinkStats totals", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT clicked_at, COALESCE(ip_hash, '')
+		FROM clicks
+		WHERE code = ? AND clicked_at >= ?
+	`, code, since.UTC())
+	if err != nil {
+		return nil, sqliteError(ctx, "GetLinkStats series", err)
+	}
+	defer rows.Close()
+
+	points := make(map[time.Time]models.StatsBucket)
+	visitors := make(map[time.Time]map[string]struct{})
+	for rows.Next() {
+		var clickedAt time.Time
+		var ipHash string
+		if err := rows.Scan(&clickedAt, &ipHash); err != nil {
+			continue
+		}
+		start := truncateToBucket(clickedAt, bucket)
+		p := points[start]
+		p.Start = start
+		p.Clicks++
+		if ipHash != "" { // like COUNT(DISTINCT ip_hash), which skips NULLs
+			if visitors[start] == nil {
+				visitors[start] = make(map[string]struct{})
+			}
+			visitors[start][ipHash] = struct{}{}
+			p.Unique = len(visitors[start])
+		}
+		points[start] = p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, sqliteError(ctx, "GetLinkStats series", err)
+	}
+	stats.Series = fillSeries(points, since, time.Now(), bucket)
+	return stats, nil
+}
+
+// ---------------------------------------------------------------------------
+// Custom domains
+// ---------------------------------------------------------------------------
+
+func (r *SQLiteRepo) CreateDomain(ctx context.Context, userID int, hostname, token string) (*models.Domain, error) {
+	d := &models.Domain{UserID: userID, Hostname: hostname, VerificationToken: token, CreatedAt: utcNow()}
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO domains (user_id, hostname, verification_token, created_at)
+		SELECT ?, ?, ?, ?
+		WHERE NOT EXISTS (SELECT 1 FROM domains WHERE hostname = ? AND verified_at IS NOT NULL)
+	`, userID, hostname, token, d.CreatedAt, hostname)
+	// conflict: already claimed by this user; no row: verified by someone else
+	if err = sqliteError(ctx, "CreateDomain", err); errors.Is(err, ErrConflict) {
+		return nil, ErrDomainTaken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrDomainTaken
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, sqliteError(ctx, "CreateDomain", err)
+	}
+	d.ID = int(id)
+	return d, nil
+}
+
+func (r *SQLiteRepo) ListDomains(ctx context.Context, userID int) ([]models.Domain, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, hostname, verification_token, verified_at, created_at
+		FROM domains
+		WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC
+	`, userID)
+	if err != nil {
+		return nil, sqliteError(ctx, "ListDomains", err)
+	}
+	defer rows.Close()
+
+	domains := []models.Domain{}
+	for rows.Next() {
+		d := models.Domain{UserID: userID}
+		var verifiedAt sql.NullTime
+		if err := rows.Scan(&d.ID, &d.Hostname, &d.VerificationToken, &verifiedAt, &d.CreatedAt); err != nil {
+			slog.ErrorContext(ctx, "❌ Scan domain error", "err", err)
+			continue
+		}
+		d.VerifiedAt = nullTimePtr(verifiedAt)
+		domains = append(domains, d)
+	}
+	return domains, sqliteError(ctx, "ListDomains", rows.Err())
+}
+
+func (r *SQLiteRepo) GetDomain(ctx context.Context, userID, id int) (*models.Domain, error) {
+	return r.scanDomain(ctx, `
+		SELECT id, user_id, hostname, verification_token, verified_at, created_at
+		FROM domains
+		WHERE id = ? AND user_id = ?
+	`, id, userID)
+}
+
+func (r *SQLiteRepo) GetVerifiedDomain(ctx context.Context, userID int, hostname string) (*models.Domain, error) {
+	return r.scanDomain(ctx, `
+		SELECT id, user_id, hostname, verification_token, verified_at, created_at
+		FROM domains
+		WHERE hostname = ? AND user_id = ? AND verified_at IS NOT NULL
+	`, hostname, userID)
+}
+
+func (r *SQLiteRepo) scanDomain(ctx context.Context, query string, args ...any) (*models.Domain, error) {
+	var d models.Domain
+	var verifiedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, args...).
+		Scan(&d.ID, &d.UserID, &d.Hostname, &d.VerificationToken, &verifiedAt, &d.CreatedAt)
+	if err != nil {
+		return nil, sqliteError(ctx, "Domain lookup", err)
+	}
+	d.VerifiedAt = nullTimePtr(verifiedAt)
+	return &d, nil
+}
+
+func (r *SQLiteRepo) MarkDomainVerified(ctx context.Context, userID, id int) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE domains SET verified_at = COALESCE(verified_at, ?)
+		WHERE id = ? AND user_id = ?
+	`, utcNow(), id, userID)
+	// idx_domains_verified_hostname: another user verified the hostname first
+	if err = sqliteError(ctx, "MarkDomainVerified", err); errors.Is(err, ErrConflict) {
+		return ErrDomainTaken
+	}
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) DeleteDomain(ctx context.Context, userID, id int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return sqliteError(ctx, "DeleteDomain begin", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM domains WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return sqliteError(ctx, "DeleteDomain", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	// done explicitly so it holds even when foreign_keys is off for this connection
+	if _, err := tx.ExecContext(ctx, `UPDATE links SET domain_id = NULL WHERE domain_id = ?`, id); err != nil {
+		return sqliteError(ctx, "DeleteDomain links", err)
+	}
+	return sqliteError(ctx, "DeleteDomain commit", tx.Commit())
+}
+
+func (r *SQLiteRepo) ResolveHost(ctx context.Context, hostname string) (int, error) {
+	var id int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id FROM domains
+		WHERE hostname = ? AND verified_at IS NOT NULL
+	`, hostname).Scan(&id)
+	if err != nil {
+		return 0, sqliteError(ctx, "ResolveHost", err)
+	}
+	return id, nil
+}
+
+// ---------------------------------------------------------------------------
+// Refresh tokens
+// ---------------------------------------------------------------------------
+
+func (r *SQLiteRepo) CreateRefreshToken(ctx context.Context, t *models.RefreshToken) error {
+	return sqliteInsertRefreshToken(ctx, r.db, t)
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func sqliteInsertRefreshToken(ctx context.Context, q execer, t *models.RefreshToken) error {
+	res, err := q.ExecContext(ctx, `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, t.UserID, t.FamilyID, t.TokenHash, t.ExpiresAt.UTC(), utcNow())
+	if err != nil {
+		return sqliteError(ctx, "CreateRefreshToken", err)
+	}
+	if t.ID, err = res.LastInsertId(); err != nil {
+		return sqliteError(ctx, "CreateRefreshToken", err)
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	t := &models.RefreshToken{TokenHash: tokenHash}
+	var revokedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, `
+		SELECT rt.id, rt.user_id, rt.family_id, rt.expires_at, rt.revoked_at, u.email, u.plan
+		FROM refresh_tokens rt
+		JOIN users u ON u.id = rt.user_id
+		WHERE rt.token_hash = ?
+	`, tokenHash).Scan(&t.ID, &t.UserID, &t.FamilyID, &t.ExpiresAt, &revokedAt, &t.Email, &t.Plan)
+	if err != nil {
+		return nil, sqliteError(ctx, "GetRefreshToken", err)
+	}
+	t.RevokedAt = nullTimePtr(revokedAt)
+	return t, nil
+}
+
+func (r *SQLiteRepo) RotateRefreshToken(ctx context.Context, id int64, next *models.RefreshToken) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return sqliteError(ctx, "RotateRefreshToken begin", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE refresh_tokens SET revoked_at = ?
+		WHERE id = ? AND revoked_at IS NULL
+	`, utcNow(), id)
+	if err != nil {
+		return sqliteError(ctx, "RotateRefreshToken revoke", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrTokenRevoked
+	}
+
+	if err := sqliteInsertRefreshToken(ctx, tx, next); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE refresh_tokens SET replaced_by = ? WHERE id = ?
+	`, next.ID, id); err != nil {
+		return sqliteError(ctx, "RotateRefreshToken link", err)
+	}
+	return sqliteError(ctx, "RotateRefreshToken commit", tx.Commit())
+}
+
+func (r *SQLiteRepo) RevokeRefreshFamily(ctx context.Context, familyID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE refresh_tokens SET revoked_at = ?
+		WHERE family_id = ? AND revoked_at IS NULL
+	`, utcNow(), familyID)
+	return sqliteError(ctx, "RevokeRefreshFamily", err)
+}
+
+// ---------------------------------------------------------------------------
+// API keys
+// ---------------------------------------------------------------------------
+
+func (r *SQLiteRepo) CreateAPIKey(ctx context.Context, userID int, name string, scopes []string, expiresAt *time.Time) (*models.APIKey, string, error) {
+	key, raw, err := newAPIKey(name, scopes, expiresAt)
+	if err != nil {
+		return nil, "", err
+	}
+	key.CreatedAt = utcNow()
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, userID, name, key.Prefix, hashAPIKey(raw), jsonStrings{&key.Scopes}, utcPtr(expiresAt), key.CreatedAt)
+	if err != nil {
+		return nil, "", sqliteError(ctx, "CreateAPIKey", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, "", sqliteError(ctx, "CreateAPIKey", err)
+	}
+	key.ID = int(id)
+	return key, raw, nil
+}
+
+func (r *SQLiteRepo) ListAPIKeys(ctx context.Context, userID int) ([]models.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, name, prefix, scopes, expires_at, last_used_at, revoked_at, created_at
+		FROM api_keys
+		WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC
+	`, userID)
+	if err != nil {
+		return nil, sqliteError(ctx, "ListAPIKeys", err)
+	}
+	return scanAPIKeys(ctx, rows, sqliteError, func(s *[]string) any { return jsonStrings{s} })
+}
+
+func (r *SQLiteRepo) RevokeAPIKey(ctx context.Context, userID, id int) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE api_keys SET revoked_at = ?
+		WHERE id = ? AND user_id = ? AND revoked_at IS NULL
+	`, utcNow(), id, userID)
+	if err != nil {
+		return sqliteError(ctx, "RevokeAPIKey", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) LookupAPIKey(ctx context.Context, raw string) (models.APIKeyOwner, error) {
+	var owner models.APIKeyOwner
+	now := utcNow()
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE api_keys SET last_used_at = ?
+		WHERE key_hash = ?
+		  AND revoked_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > ?)
+		RETURNING id, user_id, (SELECT plan FROM users WHERE users.id = api_keys.user_id), scopes
+	`, now, hashAPIKey(raw), now).Scan(&owner.KeyID, &owner.UserID, &owner.Plan, jsonStrings{&owner.Scopes})
+	if err != nil {
+		return models.APIKeyOwner{}, sqliteError(ctx, "LookupAPIKey", err)
+	}
+	return owner, nil
+}
+
+// jsonStrings stores a string slice, such as API key scopes, as a JSON
+// array in a TEXT column.
+type jsonStrings struct {
+	s *[]string
+}
+
+func (j jsonStrings) Value() (driver.Value, error) {
+	if *j.s == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(*j.s)
+	return string(b), err
+}
+
+func (j jsonStrings) Scan(src any) error {
+	switch v := src.(type) {
+	case string:
+		return json.Unmarshal([]byte(v), j.s)
+	case []byte:
+		return json.Unmarshal(v, j.s)
+	default:
+		return fmt.Errorf("jsonStrings: cannot scan %T", src)
+	}
+}
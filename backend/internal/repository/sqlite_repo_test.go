@@ -0,0 +1,256 @@
+//go:build sqlite
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brij-812/HyperLinkOS/internal/config"
+	"github.com/brij-812/HyperLinkOS/internal/database"
+	"github.com/brij-812/HyperLinkOS/internal/models"
+)
+
+// newTestSQLiteRepo migrates a fresh database file and adds users 1 to 3.
+func newTestSQLiteRepo(t *testing.T) *SQLiteRepo {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Database.Path = filepath.Join(t.TempDir(), "test.db")
+
+	t.Chdir("../..") // migrations are found relative to the working directory
+	db := database.NewSQLiteDB(cfg)
+	t.Cleanup(func() { db.Close() })
+	database.RunSQLiteMigrations(db, cfg, "up")
+
+	for id := 1; id <= 3; id++ {
+		if _, err := db.Exec(`INSERT INTO users (id, email, password_hash) VALUES (?, ?, 'x')`,
+			id, fmt.Sprintf("user%d@example.com", id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewSQLiteRepo(db)
+}
+
+func TestSQLiteSaveWithExpiry(t *testing.T) {
+	r := newTestSQLiteRepo(t)
+	ctx := context.Background()
+
+	exp := time.Now().Add(2 * time.Hour)
+	if err := r.Save(ctx, &models.Link{LongURL: "https://temp.com", Code: "t123", UserID: 1, ExpiresAt: &exp}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if u, err := r.GetURL(ctx, "t123", 0); err != nil || u != "https://temp.com" {
+		t.Fatalf("expected active link https://temp.com, got %q (%v)", u, err)
+	}
+	if c, err := r.GetCode(ctx, "https://temp.com", 1, 0); err != nil || c != "t123" {
+		t.Fatalf("expected code t123, got %q (%v)", c, err)
+	}
+	if err := r.Save(ctx, &models.Link{LongURL: "https://b.com", Code: "t123", UserID: 1}); !errors.Is(err, ErrCodeTaken) {
+		t.Fatalf("expected ErrCodeTaken, got %v", err)
+	}
+
+	past := time.Now().Add(-2 * time.Hour)
+	r.Save(ctx, &models.Link{LongURL: "https://expired.com", Code: "e123", UserID: 1, ExpiresAt: &past})
+	if _, err := r.GetURL(ctx, "e123", 0); err != ErrExpired {
+		t.Fatalf("expected ErrExpired for expired link, got %v", err)
+	}
+	if _, err := r.GetCode(ctx, "https://expired.com", 1, 0); err != ErrNotFound {
+		t.Fatalf("expected expired links not to be reused, got %v", err)
+	}
+	if _, err := r.GetURL(ctx, "nope", 0); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound for unknown code, got %v", err)
+	}
+}
+
+func TestSQLiteClickLimitedLink(t *testing.T) {
+	r := newTestSQLiteRepo(t)
+	ctx := context.Background()
+
+	limit := 2
+	r.Save(ctx, &models.Link{LongURL: "https://dl.com/file", Code: "dl1", UserID: 1, ClicksLeft: &limit})
+	r.Save(ctx, &models.Link{LongURL: "https://a.com", Code: "plain", UserID: 1})
+
+	for i := 0; i < 2; i++ {
+		if _, err := r.GetURL(ctx, "dl1", 0); err != nil {
+			t.Fatalf("redirect %d: %v", i+1, err)
+		}
+	}
+	if _, err := r.GetURL(ctx, "dl1", 0); err != ErrExhausted {
+		t.Fatalf("expected ErrExhausted after the last click, got %v", err)
+	}
+	if err := r.ConsumeClick(ctx, "dl1"); err != ErrExhausted {
+		t.Fatalf("expected ErrExhausted from ConsumeClick, got %v", err)
+	}
+	if err := r.ConsumeClick(ctx, "plain"); err != nil {
+		t.Fatalf("expected unlimited links to ignore ConsumeClick, got %v", err)
+	}
+	if err := r.ConsumeClick(ctx, "nope"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound for unknown code, got %v", err)
+	}
+}
+
+func TestSQLiteUpdateLink(t *testing.T) {
+	r := newTestSQLiteRepo(t)
+	ctx := context.Background()
+
+	r.Save(ctx, &models.Link{LongURL: "https://a.com/x", Code: "u1", UserID: 1})
+
+	newURL, shared := "https://b.com/y", true
+	exp := time.Now().Add(time.Hour)
+	l, err := r.UpdateLink(ctx, 1, "u1", models.LinkUpdate{LongURL: &newURL, Shared: &shared, SetExpiry: true, ExpiresAt: &exp})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if l.LongURL != newURL || !l.Shared || l.ExpiresAt == nil {
+		t.Fatalf("unexpected updated link %+v", l)
+	}
+	if u, err := r.GetURL(ctx, "u1", 0); err != nil || u != newURL {
+		t.Fatalf("expected redirect to %s, got %q (%v)", newURL, u, err)
+	}
+	if c, err := r.GetSharedCode(ctx, newURL); err != nil || c != "u1" {
+		t.Fatalf("expected shared code u1, got %q (%v)", c, err)
+	}
+	top, _ := r.GetTopDomains(ctx, 1, 3)
+	if top["b.com"] != 1 || top["a.com"] != 0 {
+		t.Fatalf("expected the domain count to follow the new URL, got %v", top)
+	}
+
+	// clearing the expiry
+	l, err = r.UpdateLink(ctx, 1, "u1", models.LinkUpdate{SetExpiry: true})
+	if err != nil || l.ExpiresAt != nil {
+		t.Fatalf("expected the expiry to be cleared, got %+v (%v)", l, err)
+	}
+	if _, err := r.UpdateLink(ctx, 2, "u1", models.LinkUpdate{Shared: &shared}); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound updating another user's link, got %v", err)
+	}
+
+	// scheduled, click-limited links keep both in the result
+	activates, limit := time.Now().Add(48*time.Hour), 3
+	r.Save(ctx, &models.Link{LongURL: "https://a.com/z", Code: "u2", UserID: 1, ActivatesAt: &activates, ClicksLeft: &limit})
+	if _, err := r.UpdateLink(ctx, 1, "u2", models.LinkUpdate{SetExpiry: true, ExpiresAt: &exp}); err != ErrExpiryBeforeActivation {
+		t.Fatalf("expected ErrExpiryBeforeActivation, got %v", err)
+	}
+	l, err = r.UpdateLink(ctx, 1, "u2", models.LinkUpdate{Shared: &shared})
+	if err != nil || l.ActivatesAt == nil || l.ClicksLeft == nil || *l.ClicksLeft != 3 {
+		t.Fatalf("expected activation and clicks left in the result, got %+v (%v)", l, err)
+	}
+}
+
+func TestSQLiteCleanupExpiredLinks(t *testing.T) {
+	r := newTestSQLiteRepo(t)
+	ctx := context.Background()
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	for i := 0; i < 5; i++ {
+		r.Save(ctx, &models.Link{LongURL: "https://old.com", Code: fmt.Sprintf("old%d", i), UserID: 1, ExpiresAt: &past})
+	}
+	r.Save(ctx, &models.Link{LongURL: "https://old.com", Code: "live", UserID: 1, ExpiresAt: &future})
+	r.Save(ctx, &models.Link{LongURL: "https://new.com", Code: "forever", UserID: 1})
+
+	n, err := r.CleanupExpiredLinks(ctx, 2)
+	if err != nil || n != 5 {
+		t.Fatalf("expected 5 links removed across batches, got %d (%v)", n, err)
+	}
+	links, _ := r.GetAllURLsByUser(ctx, 1)
+	if len(links) != 2 {
+		t.Fatalf("expected 2 links left, got %v", links)
+	}
+	top, _ := r.GetTopDomains(ctx, 1, 3)
+	if top["old.com"] != 1 || top["new.com"] != 1 {
+		t.Fatalf("expected domain counts to drop with the swept links, got %v", top)
+	}
+	if n, err := r.CleanupExpiredLinks(ctx, 2); err != nil || n != 0 {
+		t.Fatalf("expected nothing left to sweep, got %d (%v)", n, err)
+	}
+}
+
+func TestSQLiteLinkStats(t *testing.T) {
+	r := newTestSQLiteRepo(t)
+	ctx := context.Background()
+
+	r.Save(ctx, &models.Link{LongURL: "https://a.com", Code: "st1", UserID: 1})
+	today := time.Now().UTC()
+	yesterday := today.AddDate(0, 0, -1)
+	r.SaveClicks(ctx, []models.ClickEvent{
+		{Code: "st1", ClickedAt: yesterday, IPHash: "a"},
+		{Code: "st1", ClickedAt: yesterday, IPHash: "a"},
+		{Code: "st1", ClickedAt: today, IPHash: "a"},
+		{Code: "st1", ClickedAt: today, IPHash: "b"},
+	})
+
+	stats, err := r.GetLinkStats(ctx, 1, "st1", today.AddDate(0, 0, -2), BucketDay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalClicks != 4 || stats.UniqueVisitors != 3 {
+		t.Fatalf("expected 4 clicks and 3 daily unique visitors, got %+v", stats)
+	}
+	if n := len(stats.Series); n != 3 || stats.Series[1].Clicks != 2 || stats.Series[2].Unique != 2 {
+		t.Fatalf("unexpected daily series %+v", stats.Series)
+	}
+	if _, err := r.GetLinkStats(ctx, 2, "st1", yesterday, BucketDay); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound for another user's link, got %v", err)
+	}
+}
+
+func TestSQLiteDomainClaims(t *testing.T) {
+	checkDomainClaims(t, newTestSQLiteRepo(t))
+}
+
+func TestSQLiteDeleteDomainDetachesLinks(t *testing.T) {
+	r := newTestSQLiteRepo(t)
+	ctx := context.Background()
+
+	d, err := r.CreateDomain(ctx, 1, "go.example.com", "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.MarkDomainVerified(ctx, 1, d.ID); err != nil {
+		t.Fatal(err)
+	}
+	r.Save(ctx, &models.Link{LongURL: "https://a.com", Code: "branded", UserID: 1, DomainID: d.ID})
+	if u, err := r.GetURL(ctx, "branded", d.ID); err != nil || u != "https://a.com" {
+		t.Fatalf("expected the link on its domain, got %q (%v)", u, err)
+	}
+	if _, err := r.GetURL(ctx, "branded", 0); err != ErrNotFound {
+		t.Fatalf("expected the link to be hidden from the default host, got %v", err)
+	}
+
+	if err := r.DeleteDomain(ctx, 2, d.ID); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound deleting another user's domain, got %v", err)
+	}
+	if err := r.DeleteDomain(ctx, 1, d.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := r.ResolveHost(ctx, "go.example.com"); err != ErrNotFound {
+		t.Fatalf("expected the host to stop resolving, got %v", err)
+	}
+	if u, err := r.GetURL(ctx, "branded", 0); err != nil || u != "https://a.com" {
+		t.Fatalf("expected the link to fall back to the default host, got %q (%v)", u, err)
+	}
+}
+
+func TestSQLiteRefreshTokens(t *testing.T) {
+	r := newTestSQLiteRepo(t)
+	checkRefreshTokens(t, r)
+
+	got, err := r.GetRefreshToken(context.Background(), "h2")
+	if err != nil || got.Email != "user1@example.com" || got.Plan != "free" {
+		t.Fatalf("expected the owner's email and plan, got %+v (%v)", got, err)
+	}
+}
+
+func TestSQLiteAPIKeys(t *testing.T) {
+	r := newTestSQLiteRepo(t)
+	checkAPIKeys(t, r)
+
+	_, raw, _ := r.CreateAPIKey(context.Background(), 2, "plan", nil, nil)
+	if owner, err := r.LookupAPIKey(context.Background(), raw); err != nil || owner.Plan != "free" {
+		t.Fatalf("expected the owner's plan, got %+v (%v)", owner, err)
+	}
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/brij-812/HyperLinkOS/internal/models"
+)
+
+// Supported stats bucket sizes; the names double as Postgres date_trunc fields.
+const (
+	BucketHour = "hour"
+	BucketDay  = "day"
+)
+
+// truncateToBucket returns the UTC start of the bucket containing t.
+func truncateToBucket(t time.Time, bucket string) time.Time {
+	t = t.UTC()
+	if bucket == BucketHour {
+		return t.Truncate(time.Hour)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func nextBucket(t time.Time, bucket string) time.Time {
+	if bucket == BucketHour {
+		return t.Add(time.Hour)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+// fillSeries turns sparse per-bucket counts into a dense series from since
+// up to now, so charts don't have to guess at missing buckets.
+func fillSeries(points map[time.Time]models.StatsBucket, since, now time.Time, bucket string) []models.StatsBucket {
+	series := []models.StatsBucket{}
+	for t := truncateToBucket(since, bucket); !t.After(now); t = nextBucket(t, bucket) {
+		p, ok := points[t]
+		if !ok {
+			p = models.StatsBucket{Start: t}
+		}
+		series = append(series, p)
+	}
+	return series
+}
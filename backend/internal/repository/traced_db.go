@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/brij-812/HyperLinkOS/internal/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedDB is the *sql.DB PostgresRepo queries through: every query gets
+// its own client span, so a slow redirect shows whether Postgres was the
+// culprit. Spans cover the round trip, not iterating the returned rows.
+type tracedDB struct {
+	*sql.DB
+}
+
+func (d tracedDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := startQuery(ctx, query)
+	res, err := d.DB.ExecContext(ctx, query, args...)
+	endQuery(span, err)
+	return res, err
+}
+
+func (d tracedDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := startQuery(ctx, query)
+	rows, err := d.DB.QueryContext(ctx, query, args...)
+	endQuery(span, err)
+	return rows, err
+}
+
+func (d tracedDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := startQuery(ctx, query)
+	row := d.DB.QueryRowContext(ctx, query, args...)
+	endQuery(span, row.Err())
+	return row
+}
+
+func (d tracedDB) QueryRow(query string, args ...any) *sql.Row {
+	return d.QueryRowContext(context.Background(), query, args...)
+}
+
+func (d tracedDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (tracedTx, error) {
+	tx, err := d.DB.BeginTx(ctx, opts)
+	return tracedTx{tx}, err
+}
+
+// tracedTx traces the statements of a transaction like tracedDB.
+type tracedTx struct {
+	*sql.Tx
+}
+
+func (t tracedTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := startQuery(ctx, query)
+	res, err := t.Tx.ExecContext(ctx, query, args...)
+	endQuery(span, err)
+	return res, err
+}
+
+func (t tracedTx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := startQuery(ctx, query)
+	rows, err := t.Tx.QueryContext(ctx, query, args...)
+	endQuery(span, err)
+	return rows, err
+}
+
+func (t tracedTx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := startQuery(ctx, query)
+	row := t.Tx.QueryRowContext(ctx, query, args...)
+	endQuery(span, row.Err())
+	return row
+}
+
+// startQuery opens a span named after the statement's verb, e.g. "postgres SELECT".
+func startQuery(ctx context.Context, query string) (context.Context, trace.Span) {
+	text := strings.Join(strings.Fields(query), " ")
+	verb, _, _ := strings.Cut(text, " ")
+	verb = strings.ToUpper(verb)
+	return tracing.Tracer().Start(ctx, "postgres "+verb,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBOperationName(verb),
+			semconv.DBQueryText(text),
+		),
+	)
+}
+
+// endQuery ends span; a query matching no rows is not an error.
+func endQuery(span trace.Span, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+	}
+	tracing.End(span, err)
+}
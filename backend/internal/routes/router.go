@@ -5,37 +5,74 @@ import (
 
 	"github.com/brij-812/HyperLinkOS/internal/handlers"
 	"github.com/brij-812/HyperLinkOS/internal/middleware"
+	"github.com/brij-812/HyperLinkOS/internal/models"
+	"github.com/brij-812/HyperLinkOS/internal/utils"
 	"github.com/go-chi/chi/v5"
 )
 
 // RegisterRoutes wires up all API endpoints.
-func RegisterRoutes(r chi.Router, urlHandler *handlers.URLHandler, userHandler *handlers.UserHandler) {
+func RegisterRoutes(r chi.Router, urlHandler *handlers.URLHandler, userHandler *handlers.UserHandler, apiKeyHandler *handlers.APIKeyHandler, domainHandler *handlers.DomainHandler) {
 	// 🔹 Health check
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("OK"))
 	})
 
 	// 🔹 Public authentication routes
-	r.Post("/signup", userHandler.Signup)
-	r.Post("/login", userHandler.Login)
-	r.Post("/logout", userHandler.Logout)
+	r.Group(func(auth chi.Router) {
+		auth.Use(middleware.RateLimit(middleware.RateGroupAuth))
+		auth.Post("/signup", userHandler.Signup)
+		auth.Post("/login", userHandler.Login)
+		auth.Post("/logout", userHandler.Logout)
+		auth.Post("/token/refresh", userHandler.Refresh)
+	})
 
-	// 🔹 Protected APIs (require JWT)
+	// 🔹 Protected APIs (require JWT or API key)
 	r.Group(func(protected chi.Router) {
 		protected.Use(middleware.JWTAuth)
+		protected.Use(middleware.RateLimit(middleware.RateGroupAPI))
+
+		// ✏️ links:write
+		protected.Group(func(write chi.Router) {
+			write.Use(middleware.RequireScope(models.ScopeLinksWrite))
+
+			// 🧠 /shorten has its own, tighter limits
+			write.Group(func(limited chi.Router) {
+				limited.Use(middleware.RateLimit(middleware.RateGroupShorten))
+				limited.Post("/shorten", urlHandler.ShortenURL)
+			})
+			write.Patch("/url/{code}", urlHandler.UpdateURL)
+			write.Delete("/url/{code}", urlHandler.DeleteURL)
+		})
 
-		// 🧠 Apply rate limiting *only* on /shorten
-		protected.Group(func(limited chi.Router) {
-			limited.Use(middleware.RateLimit)
-			limited.Post("/shorten", urlHandler.ShortenURL)
+		// 📖 links:read
+		protected.Group(func(read chi.Router) {
+			read.Use(middleware.RequireScope(models.ScopeLinksRead))
+			read.Get("/all", urlHandler.GetAllUserURLs)
+			read.Get("/url/{code}/stats", urlHandler.GetLinkStats)
 		})
 
-		// Normal protected endpoints (no rate limit)
-		protected.Get("/metrics", urlHandler.GetMetrics)
-		protected.Get("/all", urlHandler.GetAllUserURLs)
-		protected.Delete("/url/{code}", urlHandler.DeleteURL)
+		// 📊 metrics:read
+		protected.With(middleware.RequireScope(models.ScopeMetricsRead)).
+			Get("/metrics", urlHandler.GetMetrics)
+
+		// 🔑 API key and domain management is limited to logged-in sessions
+		protected.Group(func(session chi.Router) {
+			session.Use(middleware.SessionOnly)
+			session.Post("/apikeys", apiKeyHandler.Create)
+			session.Get("/apikeys", apiKeyHandler.List)
+			session.Delete("/apikeys/{id}", apiKeyHandler.Revoke)
+
+			// 🌐 Custom domains
+			session.Post("/domains", domainHandler.Create)
+			session.Get("/domains", domainHandler.List)
+			session.Post("/domains/{id}/verify", domainHandler.Verify)
+			session.Delete("/domains/{id}", domainHandler.Delete)
+		})
 	})
 
-	// 🔹 Public redirect route
-	r.Get("/{shortCode:[A-Za-z0-9_-]{4,12}}", urlHandler.RedirectURL)
+	// 🔹 Public redirect route, and the password form of protected links
+	r.With(middleware.EnumerationGuard, middleware.RateLimit(middleware.RateGroupRedirect)).
+		Get("/{shortCode:"+utils.ShortCodePattern+"}", urlHandler.RedirectURL)
+	r.With(middleware.EnumerationGuard, middleware.RateLimit(middleware.RateGroupUnlock)).
+		Post("/{shortCode:"+utils.ShortCodePattern+"}", urlHandler.UnlockURL)
 }
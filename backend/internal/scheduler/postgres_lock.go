@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"hash/fnv"
+	"log/slog"
+	"time"
+)
+
+// releaseTimeout bounds the unlock issued when leadership is given up.
+const releaseTimeout = 5 * time.Second
+
+// PostgresLocker elects job leaders with session-level advisory locks. A
+// leader keeps one pooled connection per job for as long as it leads; if
+// that connection dies, Postgres drops the lock and another replica takes
+// over on its next tick.
+type PostgresLocker struct {
+	db *sql.DB
+}
+
+func NewPostgresLocker(db *sql.DB) *PostgresLocker {
+	return &PostgresLocker{db: db}
+}
+
+func (l *PostgresLocker) Acquire(ctx context.Context, name string) (Lease, bool, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	key := lockKey(name)
+	var ok bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&ok); err != nil {
+		conn.Close()
+		return nil, false, err
+	}
+	if !ok {
+		conn.Close()
+		return nil, false, nil
+	}
+	return &pgLease{conn: conn, key: key, name: name}, true, nil
+}
+
+// lockKey maps a job name onto the 64-bit advisory lock keyspace.
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("hyperlinkos:job:" + name))
+	return int64(h.Sum64())
+}
+
+type pgLease struct {
+	conn *sql.Conn
+	key  int64
+	name string
+}
+
+// Held checks the session holding the lock is still alive.
+func (l *pgLease) Held(ctx context.Context) bool {
+	_, err := l.conn.ExecContext(ctx, `SELECT 1`)
+	return err == nil
+}
+
+func (l *pgLease) Release() {
+	ctx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+	defer cancel()
+	if _, err := l.conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, l.key); err != nil {
+		slog.WarnContext(ctx, "⚠️ Failed to release leadership of job", "job", l.name, "err", err)
+		// never hand a session that may still hold the lock back to the pool
+		l.conn.Raw(func(any) error { return driver.ErrBadConn })
+	}
+	l.conn.Close()
+}
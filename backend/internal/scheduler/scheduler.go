@@ -0,0 +1,140 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Job is a task the scheduler runs every Interval.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	// Local jobs run on every replica, e.g. evicting this process's cache.
+	// All other jobs only run on the replica that leads them (see Locker).
+	Local bool
+	Run   func(ctx context.Context) error
+}
+
+// Locker elects one leader per job across replicas.
+type Locker interface {
+	// Acquire tries to become the leader for name without blocking.
+	// ok is false when another replica already leads it.
+	Acquire(ctx context.Context, name string) (lease Lease, ok bool, err error)
+}
+
+// Lease is held leadership of one job.
+type Lease interface {
+	// Held reports whether leadership is still held; a lease tied to a
+	// database session is lost when the session dies.
+	Held(ctx context.Context) bool
+	// Release gives leadership up so another replica can take over.
+	Release()
+}
+
+// Scheduler runs periodic jobs in background goroutines, one per job.
+type Scheduler struct {
+	locker Locker
+	jobs   []Job
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	once   sync.Once
+}
+
+// New creates a scheduler; a nil locker runs every job on this replica,
+// which is right for single-node deployments.
+func New(locker Locker) *Scheduler {
+	return &Scheduler{locker: locker}
+}
+
+// Add registers a job. Jobs without a positive interval are skipped.
+// Add must be called before Start.
+func (s *Scheduler) Add(job Job) {
+	if job.Interval <= 0 {
+		slog.Info("⏸️ Job disabled", "job", job.Name)
+		return
+	}
+	s.jobs = append(s.jobs, job)
+}
+
+// Start launches the job loops. Each job first runs one interval after Start.
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, job)
+		slog.InfoContext(ctx, "⏰ Scheduled job", "job", job.Name, "every", job.Interval)
+	}
+}
+
+// Close stops the loops, waits for running jobs to return and releases
+// leadership. It is safe to call more than once.
+func (s *Scheduler) Close() {
+	s.once.Do(func() {
+		if s.cancel != nil {
+			s.cancel()
+		}
+		s.wg.Wait()
+	})
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	var lease Lease
+	defer func() {
+		if lease != nil {
+			lease.Release()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !job.Local && s.locker != nil {
+			if lease != nil && !lease.Held(ctx) {
+				slog.WarnContext(ctx, "⚠️ Lost leadership of job", "job", job.Name)
+				lease.Release()
+				lease = nil
+			}
+			if lease == nil {
+				l, ok, err := s.locker.Acquire(ctx, job.Name)
+				if err != nil {
+					slog.ErrorContext(ctx, "❌ Leader election failed", "job", job.Name, "err", err)
+					continue
+				}
+				if !ok {
+					continue // another replica leads this job
+				}
+				lease = l
+				slog.InfoContext(ctx, "👑 This replica now runs job", "job", job.Name)
+			}
+		}
+		s.run(ctx, job)
+	}
+}
+
+// run executes one iteration, bounded by the job's interval so a hung run
+// can't pile up behind itself.
+func (s *Scheduler) run(ctx context.Context, job Job) {
+	defer func() {
+		if p := recover(); p != nil {
+			slog.ErrorContext(ctx, "❌ Job panicked", "job", job.Name, "panic", p)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, job.Interval)
+	defer cancel()
+	if err := job.Run(ctx); err != nil && ctx.Err() != context.Canceled {
+		slog.ErrorContext(ctx, "❌ Job failed", "job", job.Name, "err", err)
+	}
+}
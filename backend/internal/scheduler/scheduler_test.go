@@ -0,0 +1,143 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeLocker hands a job's lease to whichever caller asks first.
+type fakeLocker struct {
+	mu     sync.Mutex
+	held   map[string]*fakeLease
+	denied bool // simulates another replica leading every job
+}
+
+type fakeLease struct {
+	locker *fakeLocker
+	name   string
+	lost   atomic.Bool
+}
+
+func (l *fakeLocker) Acquire(ctx context.Context, name string) (Lease, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.denied || l.held[name] != nil {
+		return nil, false, nil
+	}
+	if l.held == nil {
+		l.held = map[string]*fakeLease{}
+	}
+	lease := &fakeLease{locker: l, name: name}
+	l.held[name] = lease
+	return lease, true, nil
+}
+
+func (l *fakeLocker) lease(name string) *fakeLease {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.held[name]
+}
+
+func (l *fakeLease) Held(ctx context.Context) bool { return !l.lost.Load() }
+
+func (l *fakeLease) Release() {
+	l.locker.mu.Lock()
+	defer l.locker.mu.Unlock()
+	if l.locker.held[l.name] == l {
+		delete(l.locker.held, l.name)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSchedulerLeaderElection(t *testing.T) {
+	locker := &fakeLocker{}
+	s := New(locker)
+
+	var runs atomic.Int32
+	s.Add(Job{Name: "sweep", Interval: 10 * time.Millisecond, Run: func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	}})
+	s.Add(Job{Name: "disabled", Interval: 0, Run: func(ctx context.Context) error {
+		t.Error("disabled job ran")
+		return nil
+	}})
+	s.Start()
+	defer s.Close()
+
+	waitFor(t, func() bool { return runs.Load() >= 2 })
+
+	// Losing the session drops the lease; the next tick takes it again.
+	first := locker.lease("sweep")
+	first.lost.Store(true)
+	waitFor(t, func() bool {
+		l := locker.lease("sweep")
+		return l != nil && l != first
+	})
+
+	s.Close()
+	if locker.lease("sweep") != nil {
+		t.Error("expected Close to release the lease")
+	}
+}
+
+func TestSchedulerFollowerAndLocalJobs(t *testing.T) {
+	locker := &fakeLocker{denied: true}
+	s := New(locker)
+
+	var leaderRuns, localRuns atomic.Int32
+	s.Add(Job{Name: "sweep", Interval: 10 * time.Millisecond, Run: func(ctx context.Context) error {
+		leaderRuns.Add(1)
+		return nil
+	}})
+	s.Add(Job{Name: "evict", Interval: 10 * time.Millisecond, Local: true, Run: func(ctx context.Context) error {
+		localRuns.Add(1)
+		return nil
+	}})
+	s.Start()
+
+	waitFor(t, func() bool { return localRuns.Load() >= 3 })
+	s.Close()
+	s.Close() // idempotent
+
+	if n := leaderRuns.Load(); n != 0 {
+		t.Errorf("expected a follower not to run leader jobs, ran %d times", n)
+	}
+}
+
+func TestSchedulerCloseWaitsForRunningJob(t *testing.T) {
+	s := New(nil)
+
+	started := make(chan struct{})
+	var finished atomic.Bool
+	s.Add(Job{Name: "slow", Interval: 10 * time.Millisecond, Run: func(ctx context.Context) error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-ctx.Done()
+		time.Sleep(20 * time.Millisecond)
+		finished.Store(true)
+		return ctx.Err()
+	}})
+	s.Start()
+
+	<-started
+	s.Close()
+	if !finished.Load() {
+		t.Error("expected Close to wait for the running job")
+	}
+}
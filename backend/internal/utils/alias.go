@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// ShortCodePattern describes every short code the redirect route accepts.
+// Custom aliases are validated against the same pattern so they stay reachable.
+const ShortCodePattern = `[A-Za-z0-9_-]{4,12}`
+
+var shortCodeRegexp = regexp.MustCompile(`^` + ShortCodePattern + `$`)
+
+var (
+	ErrInvalidAlias  = errors.New("alias must be 4-12 characters of letters, digits, '_' or '-'")
+	ErrReservedAlias = errors.New("alias is reserved")
+)
+
+// reservedAliases are paths owned by the API itself; letting users claim
+// them would either shadow a route or produce a link that never resolves.
+var reservedAliases = map[string]struct{}{
+	"health":  {},
+	"signup":  {},
+	"login":   {},
+	"logout":  {},
+	"shorten": {},
+	"metrics": {},
+	"all":     {},
+	"url":     {},
+	"token":   {},
+	"apikeys": {},
+	"domains": {},
+	"api":     {},
+	"admin":   {},
+	"static":  {},
+}
+
+// ValidateAlias checks that a user-chosen short code is well-formed and not reserved.
+func ValidateAlias(alias string) error {
+	if !shortCodeRegexp.MatchString(alias) {
+		return ErrInvalidAlias
+	}
+	if _, ok := reservedAliases[strings.ToLower(alias)]; ok {
+		return ErrReservedAlias
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultCodeLength = 6
+	MinCodeLength     = 4
+	MaxCodeLength     = 12
+
+	// SnowflakeMaxCodeLength bounds a base62 snowflake ID: codes are 10
+	// characters today and grow to 11 around 2030, where they stay.
+	SnowflakeMaxCodeLength = 11
+
+	base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// CodeGenerator produces candidate short codes for a long URL.
+// attempt starts at 0 and is increased each time the previous candidate
+// collided with an existing code, so generators can vary their output.
+type CodeGenerator interface {
+	Generate(longURL string, attempt int) (string, error)
+}
+
+// Sequence hands out monotonically increasing IDs (e.g. a Postgres sequence).
+type Sequence interface {
+	NextID() (int64, error)
+}
+
+// NewCodeGenerator builds the generator named by strategy:
+// "hash" (default), "random", "sequence" or "snowflake". The snowflake
+// length follows from the ID itself, so length must be left at 0 for it.
+func NewCodeGenerator(strategy string, length int, nodeID int64, seq Sequence) (CodeGenerator, error) {
+	if strings.EqualFold(strategy, "snowflake") {
+		if length != 0 {
+			return nil, fmt.Errorf("snowflake codes have a fixed length of up to %d characters; leave the code length unset, got %d", SnowflakeMaxCodeLength, length)
+		}
+		return NewSnowflakeGenerator(nodeID)
+	}
+	if length == 0 {
+		length = DefaultCodeLength
+	}
+	if length < MinCodeLength || length > MaxCodeLength {
+		return nil, fmt.Errorf("code length must be between %d and %d, got %d", MinCodeLength, MaxCodeLength, length)
+	}
+
+	switch strings.ToLower(strategy) {
+	case "", "hash":
+		return &HashGenerator{Length: length}, nil
+	case "random":
+		return &RandomGenerator{Length: length}, nil
+	case "sequence":
+		if seq == nil {
+			return nil, fmt.Errorf("sequence strategy requires a sequence source")
+		}
+		return &SequenceGenerator{Seq: seq, Length: length}, nil
+	default:
+		return nil, fmt.Errorf("unknown short code strategy %q", strategy)
+	}
+}
+
+// HashGenerator derives codes from a SHA-1 of the URL. Retries salt the
+// input with the attempt number so a collision yields a different code.
+type HashGenerator struct {
+	Length int
+}
+
+func (g *HashGenerator) Generate(longURL string, attempt int) (string, error) {
+	input := longURL
+	if attempt > 0 {
+		input = longURL + "#" + strconv.Itoa(attempt)
+	}
+	sum := sha1.Sum([]byte(input))
+	return base64.URLEncoding.EncodeToString(sum[:])[:g.Length], nil
+}
+
+// RandomGenerator returns uniformly random base62 codes from crypto/rand.
+type RandomGenerator struct {
+	Length int
+}
+
+func (g *RandomGenerator) Generate(_ string, _ int) (string, error) {
+	max := big.NewInt(int64(len(base62Alphabet)))
+	b := make([]byte, g.Length)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = base62Alphabet[n.Int64()]
+	}
+	return string(b), nil
+}
+
+// SequenceGenerator base62-encodes the next value of a counter, left-padded
+// to Length so early IDs still satisfy the minimum code length.
+type SequenceGenerator struct {
+	Seq    Sequence
+	Length int
+}
+
+func (g *SequenceGenerator) Generate(_ string, _ int) (string, error) {
+	id, err := g.Seq.NextID()
+	if err != nil {
+		return "", err
+	}
+	code := EncodeBase62(uint64(id))
+	if len(code) < g.Length {
+		code = strings.Repeat("0", g.Length-len(code)) + code
+	}
+	return code, nil
+}
+
+// Snowflake layout: 41 bits of milliseconds since snowflakeEpoch,
+// 10 bits of node ID and 12 bits of per-millisecond sequence.
+const (
+	snowflakeNodeBits = 10
+	snowflakeSeqBits  = 12
+	snowflakeMaxNode  = 1<<snowflakeNodeBits - 1
+	snowflakeMaxSeq   = 1<<snowflakeSeqBits - 1
+)
+
+var snowflakeEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// SnowflakeGenerator produces time-ordered IDs that are unique across nodes
+// without coordination, as long as every replica has a distinct node ID.
+type SnowflakeGenerator struct {
+	mu     sync.Mutex
+	nodeID int64
+	lastMs int64
+	seq    int64
+}
+
+func NewSnowflakeGenerator(nodeID int64) (*SnowflakeGenerator, error) {
+	if nodeID < 0 || nodeID > snowflakeMaxNode {
+		return nil, fmt.Errorf("snowflake node id must be between 0 and %d, got %d", snowflakeMaxNode, nodeID)
+	}
+	return &SnowflakeGenerator{nodeID: nodeID}, nil
+}
+
+func (g *SnowflakeGenerator) Generate(_ string, _ int) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Since(snowflakeEpoch).Milliseconds()
+	if now < g.lastMs {
+		// clock moved backwards; keep issuing from the last known timestamp
+		now = g.lastMs
+	}
+	if now == g.lastMs {
+		g.seq = (g.seq + 1) & snowflakeMaxSeq
+		if g.seq == 0 {
+			// sequence exhausted for this millisecond, wait for the next one
+			for now <= g.lastMs {
+				time.Sleep(100 * time.Microsecond)
+				now = time.Since(snowflakeEpoch).Milliseconds()
+			}
+		}
+	} else {
+		g.seq = 0
+	}
+	g.lastMs = now
+
+	id := now<<(snowflakeNodeBits+snowflakeSeqBits) | g.nodeID<<snowflakeSeqBits | g.seq
+	return EncodeBase62(uint64(id)), nil
+}
+
+// EncodeBase62 encodes n using digits, upper- and lower-case letters.
+func EncodeBase62(n uint64) string {
+	if n == 0 {
+		return "0"
+	}
+	var b []byte
+	for n > 0 {
+		b = append(b, base62Alphabet[n%62])
+		n /= 62
+	}
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"errors"
+	"net"
+	"regexp"
+	"strings"
+)
+
+var ErrInvalidHostname = errors.New("hostname must be a fully-qualified domain name")
+
+var hostnameRegexp = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}$`)
+
+// NormalizeHost lowercases a Host header value and strips any port and trailing dot,
+// so "Go.Example.com.:443" and "go.example.com" compare equal.
+func NormalizeHost(host string) string {
+	host = strings.TrimSpace(host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// ValidateHostname normalizes a user-supplied custom domain and checks it is a plain FQDN.
+func ValidateHostname(host string) (string, error) {
+	host = NormalizeHost(host)
+	if len(host) > 253 || !hostnameRegexp.MatchString(host) {
+		return "", ErrInvalidHostname
+	}
+	return host, nil
+}
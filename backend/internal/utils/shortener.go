@@ -1,14 +1,7 @@
 package utils
 
-import (
-	"crypto/sha1"
-	"encoding/base64"
-)
-
+// GenerateShortCode returns the default deterministic 6-character code for a URL.
 func GenerateShortCode(url string) string {
-	h := sha1.New()
-	h.Write([]byte(url))
-	hash := h.Sum(nil)
-	code := base64.URLEncoding.EncodeToString(hash)
-	return code[:6]
+	code, _ := (&HashGenerator{Length: DefaultCodeLength}).Generate(url, 0)
+	return code
 }
@@ -12,3 +12,59 @@ func TestGenerateShortCode(t *testing.T) {
 		t.Fatal("expected non-empty short code")
 	}
 }
+
+type counter struct{ n int64 }
+
+func (c *counter) NextID() (int64, error) {
+	c.n++
+	return c.n, nil
+}
+
+func TestCodeGeneratorStrategies(t *testing.T) {
+	for _, strategy := range []string{"hash", "random", "sequence", "snowflake"} {
+		length := 8
+		if strategy == "snowflake" {
+			length = 0 // fixed length
+		}
+		gen, err := NewCodeGenerator(strategy, length, 1, &counter{})
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", strategy, err)
+		}
+
+		seen := make(map[string]bool)
+		for attempt := 0; attempt < 50; attempt++ {
+			code, err := gen.Generate("https://example.com", attempt)
+			if err != nil {
+				t.Fatalf("%s: generate failed: %v", strategy, err)
+			}
+			if !shortCodeRegexp.MatchString(code) {
+				t.Fatalf("%s: code %q does not match the redirect route pattern", strategy, code)
+			}
+			if seen[code] {
+				t.Fatalf("%s: duplicate code %q on attempt %d", strategy, code, attempt)
+			}
+			seen[code] = true
+		}
+	}
+}
+
+func TestNewCodeGeneratorRejectsBadConfig(t *testing.T) {
+	if _, err := NewCodeGenerator("hash", 3, 0, nil); err == nil {
+		t.Fatal("expected error for too-short length")
+	}
+	if _, err := NewCodeGenerator("sequence", 6, 0, nil); err == nil {
+		t.Fatal("expected error for sequence strategy without a sequence")
+	}
+	if _, err := NewCodeGenerator("snowflake", 0, 5000, nil); err == nil {
+		t.Fatal("expected error for out-of-range node id")
+	}
+	if _, err := NewCodeGenerator("snowflake", 8, 1, nil); err == nil {
+		t.Fatal("expected error for a code length with the snowflake strategy")
+	}
+	if _, err := NewCodeGenerator("snowflake", 6, 5000, nil); err == nil {
+		t.Fatal("expected error for out-of-range node id")
+	}
+	if _, err := NewCodeGenerator("uuid", 6, 0, nil); err == nil {
+		t.Fatal("expected error for unknown strategy")
+	}
+}
@@ -0,0 +1,3 @@
+-- Counter backing the "sequence" short code strategy.
+-- Starts at 62^3 so the very first base62 code is already 4 characters long.
+CREATE SEQUENCE IF NOT EXISTS link_code_seq START WITH 238328;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_links_shared_long_url;
+DROP INDEX IF EXISTS idx_links_user_long_url;
+
+ALTER TABLE links
+DROP COLUMN IF EXISTS shared;
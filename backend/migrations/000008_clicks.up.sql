@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS clicks (
+    id BIGSERIAL PRIMARY KEY,
+    code TEXT NOT NULL,
+    clicked_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    referrer TEXT,
+    user_agent TEXT,
+    ip_hash TEXT,
+    country TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_clicks_code_clicked_at ON clicks (code, clicked_at);
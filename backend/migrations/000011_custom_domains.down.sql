@@ -0,0 +1,4 @@
+ALTER TABLE links
+DROP COLUMN IF EXISTS domain_id;
+
+DROP TABLE IF EXISTS domains;
@@ -0,0 +1,15 @@
+-- Per-user custom domains, verified via a DNS TXT record
+CREATE TABLE IF NOT EXISTS domains (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    hostname TEXT UNIQUE NOT NULL,
+    verification_token TEXT NOT NULL,
+    verified_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_domains_user ON domains (user_id);
+
+-- NULL = default public domain. Removing a domain moves its links back there.
+ALTER TABLE links
+ADD COLUMN IF NOT EXISTS domain_id INT REFERENCES domains(id) ON DELETE SET NULL;
@@ -0,0 +1,3 @@
+-- Optional bcrypt hash gating a link behind a password; NULL = public.
+ALTER TABLE links
+ADD COLUMN IF NOT EXISTS password_hash TEXT DEFAULT NULL;
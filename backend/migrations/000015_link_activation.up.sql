@@ -0,0 +1,3 @@
+-- Links with activates_at in the future don't redirect yet; NULL = live on creation.
+ALTER TABLE links
+ADD COLUMN IF NOT EXISTS activates_at TIMESTAMPTZ DEFAULT NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_links_expires_at;
+DROP TABLE IF EXISTS click_rollups;
@@ -0,0 +1,11 @@
+-- Per-day click totals per link, maintained by the click rollup job.
+CREATE TABLE IF NOT EXISTS click_rollups (
+    code TEXT NOT NULL,
+    day DATE NOT NULL,
+    clicks INT NOT NULL,
+    unique_visitors INT NOT NULL,
+    PRIMARY KEY (code, day)
+);
+
+-- Lets the expiry sweep find expired links without a full scan.
+CREATE INDEX IF NOT EXISTS idx_links_expires_at ON links (expires_at) WHERE expires_at IS NOT NULL;
@@ -0,0 +1,7 @@
+-- ACME account keys and certificates shared by all replicas
+-- (server.tls.acme.store: postgres). Keys are autocert cache keys.
+CREATE TABLE IF NOT EXISTS acme_cache (
+    key TEXT PRIMARY KEY,
+    data BYTEA NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
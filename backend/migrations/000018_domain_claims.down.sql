@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_domains_verified_hostname;
+
+ALTER TABLE domains DROP CONSTRAINT IF EXISTS domains_user_hostname_key;
+
+-- Fails while several users claim the same hostname; delete the extra claims first.
+ALTER TABLE domains
+ADD CONSTRAINT domains_hostname_key UNIQUE (hostname);
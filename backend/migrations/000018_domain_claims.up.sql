@@ -0,0 +1,9 @@
+-- A hostname only belongs to whoever verifies it first. Unverified claims
+-- no longer block it for everyone else; a user can hold one claim per hostname.
+ALTER TABLE domains DROP CONSTRAINT IF EXISTS domains_hostname_key;
+
+ALTER TABLE domains
+ADD CONSTRAINT domains_user_hostname_key UNIQUE (user_id, hostname);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_domains_verified_hostname
+ON domains (hostname) WHERE verified_at IS NOT NULL;
@@ -0,0 +1,6 @@
+-- Daily rollups are complete for every day before rolled_before. Stats read
+-- those days from click_rollups and everything later from clicks.
+CREATE TABLE IF NOT EXISTS click_rollup_state (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    rolled_before DATE NOT NULL
+);
@@ -0,0 +1,3 @@
+-- No-op: Postgres stores API key scopes as TEXT[]; only the SQLite
+-- migration changes their encoding.
+-- Kept so version numbers match the SQLite migrations.
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS links (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    code TEXT UNIQUE NOT NULL,
+    long_url TEXT NOT NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- SQLite cannot change a primary key in place, so domain_counts starts out
+-- with the per-user key that Postgres reaches in 000003/000004.
+CREATE TABLE IF NOT EXISTS domain_counts (
+    domain TEXT NOT NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    count INTEGER DEFAULT 1,
+    PRIMARY KEY (domain, user_id)
+);
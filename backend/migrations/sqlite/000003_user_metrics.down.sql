@@ -0,0 +1,2 @@
+-- No-op: domain_counts.user_id is created in 000002.
+-- Kept so version numbers match the Postgres migrations.
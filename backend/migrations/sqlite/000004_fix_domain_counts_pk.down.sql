@@ -0,0 +1,2 @@
+-- No-op: the (domain, user_id) primary key is created in 000002.
+-- Kept so version numbers match the Postgres migrations.
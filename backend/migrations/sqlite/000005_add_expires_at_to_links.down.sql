@@ -0,0 +1,2 @@
+ALTER TABLE links
+DROP COLUMN expires_at;
@@ -0,0 +1,2 @@
+ALTER TABLE links
+ADD COLUMN expires_at DATETIME DEFAULT NULL;
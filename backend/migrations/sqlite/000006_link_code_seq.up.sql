@@ -0,0 +1,8 @@
+-- Counter backing the "sequence" short code strategy (SQLite has no sequences).
+-- The first value handed out is 62^3 so the very first base62 code is already 4 characters long.
+CREATE TABLE IF NOT EXISTS link_code_seq (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    value INTEGER NOT NULL
+);
+
+INSERT OR IGNORE INTO link_code_seq (id, value) VALUES (1, 238327);
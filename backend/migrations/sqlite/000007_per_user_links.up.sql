@@ -0,0 +1,8 @@
+-- Links are owned per user; the same long URL may now exist once per owner.
+ALTER TABLE links
+ADD COLUMN shared BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_links_user_long_url ON links (user_id, long_url);
+
+-- Lookup of links whose owners opted in to sharing
+CREATE INDEX IF NOT EXISTS idx_links_shared_long_url ON links (long_url) WHERE shared;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS clicks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    code TEXT NOT NULL,
+    clicked_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    referrer TEXT,
+    user_agent TEXT,
+    ip_hash TEXT,
+    country TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_clicks_code_clicked_at ON clicks (code, clicked_at);
@@ -0,0 +1,21 @@
+-- SQLite cannot drop a column that carries a foreign key, so links is rebuilt without domain_id.
+CREATE TABLE links_without_domain (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    code TEXT UNIQUE NOT NULL,
+    long_url TEXT NOT NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME DEFAULT NULL,
+    shared BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+INSERT INTO links_without_domain (id, code, long_url, user_id, created_at, expires_at, shared)
+SELECT id, code, long_url, user_id, created_at, expires_at, shared FROM links;
+
+DROP TABLE links;
+ALTER TABLE links_without_domain RENAME TO links;
+
+CREATE INDEX IF NOT EXISTS idx_links_user_long_url ON links (user_id, long_url);
+CREATE INDEX IF NOT EXISTS idx_links_shared_long_url ON links (long_url) WHERE shared;
+
+DROP TABLE IF EXISTS domains;
@@ -0,0 +1,15 @@
+-- Per-user custom domains, verified via a DNS TXT record
+CREATE TABLE IF NOT EXISTS domains (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    hostname TEXT UNIQUE NOT NULL,
+    verification_token TEXT NOT NULL,
+    verified_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_domains_user ON domains (user_id);
+
+-- NULL = default public domain. Removing a domain moves its links back there.
+ALTER TABLE links
+ADD COLUMN domain_id INTEGER REFERENCES domains(id) ON DELETE SET NULL;
@@ -0,0 +1,3 @@
+-- Plan tier of each user; rate limits can be configured per plan.
+ALTER TABLE users
+ADD COLUMN plan TEXT NOT NULL DEFAULT 'free';
@@ -0,0 +1,2 @@
+ALTER TABLE links
+DROP COLUMN clicks_left;
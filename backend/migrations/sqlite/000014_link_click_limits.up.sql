@@ -0,0 +1,3 @@
+-- Remaining redirects of a click-limited link; NULL = unlimited.
+ALTER TABLE links
+ADD COLUMN clicks_left INTEGER DEFAULT NULL CHECK (clicks_left >= 0);
@@ -0,0 +1,3 @@
+-- Click rollups are Postgres-only; this only indexes expiry for the sweep.
+-- Kept so version numbers match the Postgres migrations.
+CREATE INDEX IF NOT EXISTS idx_links_expires_at ON links (expires_at) WHERE expires_at IS NOT NULL;
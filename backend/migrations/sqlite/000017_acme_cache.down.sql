@@ -0,0 +1,2 @@
+-- No-op: the Postgres certificate store is not available with SQLite.
+-- Kept so version numbers match the Postgres migrations.
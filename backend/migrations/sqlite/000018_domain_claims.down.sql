@@ -0,0 +1,28 @@
+-- Rebuilds domains with UNIQUE (hostname) again, keeping links' domain IDs
+-- as in the up migration. Fails while several users claim the same
+-- hostname; delete the extra claims first.
+CREATE TABLE domains_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    hostname TEXT UNIQUE NOT NULL,
+    verification_token TEXT NOT NULL,
+    verified_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO domains_old (id, user_id, hostname, verification_token, verified_at, created_at)
+SELECT id, user_id, hostname, verification_token, verified_at, created_at FROM domains;
+
+CREATE TEMP TABLE link_domains AS
+SELECT id, domain_id FROM links WHERE domain_id IS NOT NULL;
+
+DROP TABLE domains;
+ALTER TABLE domains_old RENAME TO domains;
+
+UPDATE links
+SET domain_id = (SELECT domain_id FROM link_domains WHERE link_domains.id = links.id)
+WHERE id IN (SELECT id FROM link_domains);
+
+DROP TABLE link_domains;
+
+CREATE INDEX IF NOT EXISTS idx_domains_user ON domains (user_id);
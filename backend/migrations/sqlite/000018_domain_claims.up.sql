@@ -0,0 +1,34 @@
+-- A hostname only belongs to whoever verifies it first. Unverified claims
+-- no longer block it for everyone else; a user can hold one claim per hostname.
+--
+-- SQLite cannot drop the inline UNIQUE (hostname), so domains is rebuilt.
+-- Dropping it fires ON DELETE SET NULL on links, so their domain IDs are
+-- saved first and restored afterwards.
+CREATE TABLE domains_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    hostname TEXT NOT NULL,
+    verification_token TEXT NOT NULL,
+    verified_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, hostname)
+);
+
+INSERT INTO domains_new (id, user_id, hostname, verification_token, verified_at, created_at)
+SELECT id, user_id, hostname, verification_token, verified_at, created_at FROM domains;
+
+CREATE TEMP TABLE link_domains AS
+SELECT id, domain_id FROM links WHERE domain_id IS NOT NULL;
+
+DROP TABLE domains;
+ALTER TABLE domains_new RENAME TO domains;
+
+UPDATE links
+SET domain_id = (SELECT domain_id FROM link_domains WHERE link_domains.id = links.id)
+WHERE id IN (SELECT id FROM link_domains);
+
+DROP TABLE link_domains;
+
+CREATE INDEX IF NOT EXISTS idx_domains_user ON domains (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_domains_verified_hostname
+ON domains (hostname) WHERE verified_at IS NOT NULL;
@@ -0,0 +1,2 @@
+-- No-op: click rollups are Postgres-only.
+-- Kept so version numbers match the Postgres migrations.
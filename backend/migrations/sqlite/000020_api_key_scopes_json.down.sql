@@ -0,0 +1,22 @@
+CREATE TABLE api_keys_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    key_hash TEXT UNIQUE NOT NULL,
+    scopes TEXT NOT NULL DEFAULT '{}',
+    expires_at DATETIME,
+    last_used_at DATETIME,
+    revoked_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO api_keys_old (id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, created_at)
+SELECT id, user_id, name, prefix, key_hash,
+       '{' || replace(substr(scopes, 2, length(scopes) - 2), '"', '') || '}',
+       expires_at, last_used_at, revoked_at, created_at
+FROM api_keys;
+
+DROP TABLE api_keys;
+ALTER TABLE api_keys_old RENAME TO api_keys;
+CREATE INDEX IF NOT EXISTS idx_api_keys_user ON api_keys (user_id);
@@ -0,0 +1,28 @@
+-- Store API key scopes as a JSON array such as ["links:read","links:write"]
+-- instead of a Postgres array literal. SQLite can't change a column default,
+-- so the table is rebuilt; nothing references api_keys.
+CREATE TABLE api_keys_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    key_hash TEXT UNIQUE NOT NULL,
+    scopes TEXT NOT NULL DEFAULT '[]',
+    expires_at DATETIME,
+    last_used_at DATETIME,
+    revoked_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Scope names never contain commas or quotes, so '{a,b}' maps to '["a","b"]'.
+INSERT INTO api_keys_new (id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, created_at)
+SELECT id, user_id, name, prefix, key_hash,
+       CASE WHEN scopes IN ('', '{}') THEN '[]'
+            ELSE '["' || replace(substr(scopes, 2, length(scopes) - 2), ',', '","') || '"]'
+       END,
+       expires_at, last_used_at, revoked_at, created_at
+FROM api_keys;
+
+DROP TABLE api_keys;
+ALTER TABLE api_keys_new RENAME TO api_keys;
+CREATE INDEX IF NOT EXISTS idx_api_keys_user ON api_keys (user_id);